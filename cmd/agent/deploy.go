@@ -6,19 +6,24 @@ package agent
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
 	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
 	"github.com/tschaefer/finchctl/internal/agent"
+	"github.com/tschaefer/finchctl/internal/inventory"
+	"github.com/tschaefer/finchctl/internal/target"
 )
 
 var deployCmd = &cobra.Command{
 	Use:               "deploy [user@]host[:port]",
 	Short:             "Deploy a Finch agent to a remote host",
-	Args:              cobra.ExactArgs(1),
+	Args:              cobra.MaximumNArgs(1),
 	Run:               runDeployCmd,
 	ValidArgsFunction: completion.CompleteHostName,
 }
@@ -27,7 +32,9 @@ func init() {
 	deployCmd.Flags().String("agent.config", "", "path to agent configuration file")
 	deployCmd.Flags().String("run.format", "progress", "output format")
 	deployCmd.Flags().Bool("run.dry-run", false, "perform a dry run without deploying the agent")
+	deployCmd.Flags().String("run.plan-out", "", "write the commands of a dry run as shell script to the given file")
 	deployCmd.Flags().String("alloy.version", "latest", "version of Alloy to install")
+	addInventoryFlags(deployCmd, true)
 
 	_ = deployCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
 }
@@ -38,24 +45,54 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	cobra.CheckErr(err)
 
 	config, _ := cmd.Flags().GetString("agent.config")
-	if config == "" {
-		errors.CheckErr(fmt.Errorf("agent configuration file must be specified"), formatType)
-	}
 	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	planOut, _ := cmd.Flags().GetString("run.plan-out")
+	alloyVersion, _ := cmd.Flags().GetString("alloy.version")
 
-	targetUrl := args[0]
+	hosts, fleet := getHosts(cmd, args, formatType)
+	for i := range hosts {
+		if hosts[i].AgentConfig == "" {
+			hosts[i].AgentConfig = config
+		}
+		if hosts[i].AgentConfig == "" {
+			errors.CheckErr(fmt.Errorf("agent configuration file must be specified"), formatType)
+		}
+		if hosts[i].AlloyVersion == "" {
+			hosts[i].AlloyVersion = alloyVersion
+		}
+	}
 
-	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
-	a, err := agent.New(cmd.Context(), agent.Options{
-		Config:     config,
-		TargetURL:  targetUrl,
-		Format:     formatType,
-		DryRun:     dryRun,
-		CmdTimeout: time.Duration(timeout) * time.Second,
-	})
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
 	errors.CheckErr(err, formatType)
 
-	alloyVersion, _ := cmd.Flags().GetString("alloy.version")
-	err = a.Deploy(alloyVersion)
-	errors.CheckErr(err, formatType)
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
+
+	runHosts(cmd, hosts, fleet, formatType, "deployment", func(host inventory.Host, out io.Writer) error {
+		sshOpts, err := ssh.GetHostOptions(cmd, host.SSH)
+		if err != nil {
+			return err
+		}
+
+		a, err := agent.New(cmd.Context(), agent.Options{
+			Config:     host.AgentConfig,
+			TargetURL:  host.URL,
+			Format:     formatType,
+			DryRun:     dryRun,
+			PlanOut:    planOut,
+			CmdTimeout: time.Duration(timeout) * time.Second,
+			Escalation: escalationType,
+			Retry:      retry,
+			SSH:        sshOpts,
+			Output:     out,
+		})
+		if err != nil {
+			return err
+		}
+
+		return a.Deploy(host.AlloyVersion)
+	})
 }
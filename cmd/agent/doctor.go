@@ -7,6 +7,7 @@ package agent
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"time"
@@ -14,7 +15,10 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
+	"github.com/tschaefer/finchctl/cmd/ssh"
 	"github.com/tschaefer/finchctl/internal/agent"
+	"github.com/tschaefer/finchctl/internal/inventory"
 	"github.com/tschaefer/finchctl/internal/target"
 
 	"github.com/olekukonko/tablewriter"
@@ -23,7 +27,7 @@ import (
 var doctorCmd = &cobra.Command{
 	Use:               "doctor [user@]host[:port]",
 	Short:             "Verify target is healthy",
-	Args:              cobra.ExactArgs(1),
+	Args:              cobra.MaximumNArgs(1),
 	Run:               runDoctorCmd,
 	ValidArgsFunction: completion.CompleteHostName,
 }
@@ -32,42 +36,80 @@ func init() {
 	doctorCmd.Flags().Bool("output.json", false, "output in JSON format")
 	doctorCmd.Flags().Bool("check.ports", false, "check agent listen ports")
 	doctorCmd.Flags().Bool("check.optionals", false, "check agent optional tools (remote setup)")
+	addInventoryFlags(doctorCmd, true)
 }
 
 func runDoctorCmd(cmd *cobra.Command, args []string) {
-	targetUrl := args[0]
-
 	jsonOutput, _ := cmd.Flags().GetBool("output.json")
 	if jsonOutput {
 		_ = os.Setenv("NO_COLOR", "1")
 	}
 
-	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
-	a, err := agent.New(cmd.Context(), agent.Options{
-		TargetURL:  targetUrl,
-		Format:     target.FormatQuiet,
-		CmdTimeout: time.Duration(timeout) * time.Second,
-	})
+	hosts, fleet := getHosts(cmd, args, target.FormatQuiet)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
 	errors.CheckErr(err, target.FormatQuiet)
 
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
 	checkPorts, _ := cmd.Flags().GetBool("check.ports")
 	checkOptionals, _ := cmd.Flags().GetBool("check.optionals")
-	list, ok := a.Doctor(checkOptionals, checkPorts)
 
-	if jsonOutput {
-		out, e := json.MarshalIndent(list, "", "  ")
-		errors.CheckErr(e, target.FormatJSON)
-		fmt.Println(string(out))
-	} else {
-		t := tablewriter.NewWriter(os.Stdout)
-		t.Header([]string{"Requirement", "Status", "Optional"})
-		for _, item := range *list {
-			_ = t.Append([]string{item.Requirement, item.Status, strconv.FormatBool(item.Optional)})
-		}
-		_ = t.Render()
+	formatType := target.FormatQuiet
+	if jsonOutput && fleet {
+		formatType = target.FormatJSON
 	}
 
-	if !ok {
-		os.Exit(1)
-	}
+	runHosts(cmd, hosts, fleet, formatType, "doctor", func(host inventory.Host, out io.Writer) error {
+		sshOpts, err := ssh.GetHostOptions(cmd, host.SSH)
+		if err != nil {
+			return err
+		}
+
+		a, err := agent.New(cmd.Context(), agent.Options{
+			TargetURL:  host.URL,
+			Format:     target.FormatQuiet,
+			CmdTimeout: time.Duration(timeout) * time.Second,
+			Escalation: escalationType,
+			Retry:      retry,
+			SSH:        sshOpts,
+			Output:     out,
+		})
+		if err != nil {
+			return err
+		}
+
+		list, ok := a.Doctor(checkOptionals, checkPorts)
+
+		switch {
+		case jsonOutput && fleet:
+			data, err := json.Marshal(map[string]any{"health": list})
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(out, string(data))
+		case jsonOutput:
+			data, err := json.MarshalIndent(list, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(out, string(data))
+		default:
+			t := tablewriter.NewWriter(out)
+			t.Header([]string{"Requirement", "Status", "Optional"})
+			for _, item := range *list {
+				_ = t.Append([]string{item.Requirement, item.Status, strconv.FormatBool(item.Optional)})
+			}
+			_ = t.Render()
+		}
+
+		if !ok {
+			return fmt.Errorf("target is unhealthy")
+		}
+
+		return nil
+	})
 }
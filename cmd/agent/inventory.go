@@ -0,0 +1,120 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package agent
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tschaefer/finchctl/cmd/completion"
+	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/ssh"
+	"github.com/tschaefer/finchctl/internal/inventory"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func addInventoryFlags(cmd *cobra.Command, parallel bool) {
+	cmd.Flags().String("inventory", "", "path to inventory file listing the hosts")
+	cmd.Flags().StringSlice("limit", nil, "limit the inventory to hosts in the given groups or with labels given as key=value")
+	if parallel {
+		cmd.Flags().Uint("parallel", 5, "number of inventory hosts to run on at the same time")
+	}
+
+	_ = cmd.RegisterFlagCompletionFunc("limit", completion.CompleteInventoryGroup)
+}
+
+// getHosts returns the hosts selected from the inventory, or the hosts given
+// as arguments. More than one host are run as fleet.
+func getHosts(cmd *cobra.Command, args []string, formatType target.Format) ([]inventory.Host, bool) {
+	file, _ := cmd.Flags().GetString("inventory")
+	limits, _ := cmd.Flags().GetStringSlice("limit")
+
+	var hosts []inventory.Host
+	if file == "" {
+		if len(args) == 0 {
+			errors.CheckErr(fmt.Errorf("target host or inventory must be specified"), formatType)
+		}
+		if len(limits) != 0 {
+			errors.CheckErr(fmt.Errorf("limit requires an inventory"), formatType)
+		}
+		for _, arg := range args {
+			hosts = append(hosts, inventory.Host{URL: arg})
+		}
+		if len(hosts) == 1 {
+			return hosts, false
+		}
+	} else {
+		if len(args) != 0 {
+			errors.CheckErr(fmt.Errorf("target host and inventory are mutually exclusive"), formatType)
+		}
+
+		inv, err := inventory.Load(file)
+		errors.CheckErr(err, formatType)
+
+		hosts, err = inv.Select(limits)
+		errors.CheckErr(err, formatType)
+
+		for _, host := range hosts {
+			if _, err := ssh.GetHostOptions(cmd, host.SSH); err != nil {
+				errors.CheckErr(fmt.Errorf("invalid inventory host %s: %w", host.URL, err), formatType)
+			}
+		}
+	}
+
+	if planOut, _ := cmd.Flags().GetString("run.plan-out"); planOut != "" {
+		errors.CheckErr(fmt.Errorf("plan output is not supported for more than one host"), formatType)
+	}
+
+	return hosts, true
+}
+
+// runHosts calls f for the hosts. A fleet is run in parallel, if the command
+// allows it, and summarized in a table. A single host is run as is.
+func runHosts(cmd *cobra.Command, hosts []inventory.Host, fleet bool, formatType target.Format, action string, f func(host inventory.Host, out io.Writer) error) {
+	if !fleet {
+		errors.CheckErr(f(hosts[0], os.Stdout), formatType)
+		return
+	}
+
+	parallel := uint(1)
+	if cmd.Flags().Lookup("parallel") != nil {
+		parallel, _ = cmd.Flags().GetUint("parallel")
+	}
+	results := inventory.Run(os.Stdout, hosts, parallel, formatType, f)
+	inventory.PrintResults(os.Stdout, results, formatType)
+	if failed := inventory.Failed(results); failed > 0 {
+		errors.CheckErr(fmt.Errorf("%s failed on %d of %d hosts", action, failed, len(results)), formatType)
+	}
+}
+
+// rollHosts calls f for the hosts in a rolling manner, see inventory.Rollout.
+// A single host is run as is.
+func rollHosts(cmd *cobra.Command, hosts []inventory.Host, fleet bool, formatType target.Format, action string, f func(host inventory.Host, out io.Writer) error) {
+	if !fleet {
+		errors.CheckErr(f(hosts[0], os.Stdout), formatType)
+		return
+	}
+
+	batchSize, _ := cmd.Flags().GetUint("batch-size")
+	maxFailures, _ := cmd.Flags().GetUint("max-failures")
+	pause, _ := cmd.Flags().GetUint("batch-pause")
+	results, stopped := inventory.Rollout(os.Stdout, hosts, inventory.RolloutOptions{
+		BatchSize:   batchSize,
+		MaxFailures: maxFailures,
+		Pause:       time.Duration(pause) * time.Second,
+	}, formatType, f)
+
+	inventory.PrintResults(os.Stdout, results, formatType)
+	failed := inventory.Failed(results)
+	if stopped {
+		errors.CheckErr(fmt.Errorf("%s stopped after %d failed hosts, %d hosts skipped", action, failed, inventory.Skipped(results)), formatType)
+	}
+	if failed > 0 {
+		errors.CheckErr(fmt.Errorf("%s failed on %d of %d hosts", action, failed, len(results)), formatType)
+	}
+}
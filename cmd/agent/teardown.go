@@ -5,19 +5,24 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package agent
 
 import (
+	"io"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
 	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
 	"github.com/tschaefer/finchctl/internal/agent"
+	"github.com/tschaefer/finchctl/internal/inventory"
+	"github.com/tschaefer/finchctl/internal/target"
 )
 
 var teardownCmd = &cobra.Command{
 	Use:               "teardown [user@]host[:port]",
 	Short:             "Tear down Finch agent from a remote host",
-	Args:              cobra.ExactArgs(1),
+	Args:              cobra.MaximumNArgs(1),
 	Run:               runTeardownCmd,
 	ValidArgsFunction: completion.CompleteHostName,
 }
@@ -25,6 +30,8 @@ var teardownCmd = &cobra.Command{
 func init() {
 	teardownCmd.Flags().String("run.format", "progress", "output format")
 	teardownCmd.Flags().Bool("run.dry-run", false, "perform a dry run without tearing down the agent")
+	teardownCmd.Flags().String("run.plan-out", "", "write the commands of a dry run as shell script to the given file")
+	addInventoryFlags(teardownCmd, true)
 
 	_ = teardownCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
 }
@@ -35,18 +42,40 @@ func runTeardownCmd(cmd *cobra.Command, args []string) {
 	cobra.CheckErr(err)
 
 	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	planOut, _ := cmd.Flags().GetString("run.plan-out")
 
-	targetUrl := args[0]
+	hosts, fleet := getHosts(cmd, args, formatType)
 
-	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
-	a, err := agent.New(cmd.Context(), agent.Options{
-		TargetURL:  targetUrl,
-		Format:     formatType,
-		DryRun:     dryRun,
-		CmdTimeout: time.Duration(timeout) * time.Second,
-	})
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
 	errors.CheckErr(err, formatType)
 
-	err = a.Teardown()
-	errors.CheckErr(err, formatType)
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
+
+	runHosts(cmd, hosts, fleet, formatType, "teardown", func(host inventory.Host, out io.Writer) error {
+		sshOpts, err := ssh.GetHostOptions(cmd, host.SSH)
+		if err != nil {
+			return err
+		}
+
+		a, err := agent.New(cmd.Context(), agent.Options{
+			TargetURL:  host.URL,
+			Format:     formatType,
+			DryRun:     dryRun,
+			PlanOut:    planOut,
+			CmdTimeout: time.Duration(timeout) * time.Second,
+			Escalation: escalationType,
+			Retry:      retry,
+			SSH:        sshOpts,
+			Output:     out,
+		})
+		if err != nil {
+			return err
+		}
+
+		return a.Teardown()
+	})
 }
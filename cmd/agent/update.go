@@ -6,19 +6,24 @@ package agent
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
 	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
 	"github.com/tschaefer/finchctl/internal/agent"
+	"github.com/tschaefer/finchctl/internal/inventory"
+	"github.com/tschaefer/finchctl/internal/target"
 )
 
 var updateCmd = &cobra.Command{
-	Use:               "update [user@]host[:port]",
-	Short:             "Update a Finch agent on a remote host",
-	Args:              cobra.ExactArgs(1),
+	Use:               "update [user@]host[:port]...",
+	Short:             "Update a Finch agent on remote hosts",
+	Args:              cobra.ArbitraryArgs,
 	Run:               runUpdateCmd,
 	ValidArgsFunction: completion.CompleteHostName,
 }
@@ -27,9 +32,15 @@ func init() {
 	updateCmd.Flags().String("agent.config", "", "path to agent configuration file")
 	updateCmd.Flags().String("run.format", "progress", "output format")
 	updateCmd.Flags().Bool("run.dry-run", false, "perform a dry run without updating the agent")
+	updateCmd.Flags().String("run.plan-out", "", "write the commands of a dry run as shell script to the given file")
 	updateCmd.Flags().Bool("skip.config", false, "skip configuration file update")
 	updateCmd.Flags().Bool("skip.binaries", false, "skip binaries update")
 	updateCmd.Flags().String("alloy.version", "latest", "version of Alloy to install")
+	updateCmd.Flags().Uint("batch-size", 1, "number of hosts to update at the same time")
+	updateCmd.Flags().Uint("max-failures", 0, "number of failed hosts to tolerate before stopping the rollout")
+	updateCmd.Flags().Uint("batch-pause", 0, "pause in seconds between batches")
+	updateCmd.Flags().Bool("health-gate", false, "wait for Alloy to be ready on each updated host before continuing")
+	addInventoryFlags(updateCmd, false)
 
 	_ = updateCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
 }
@@ -48,23 +59,62 @@ func runUpdateCmd(cmd *cobra.Command, args []string) {
 		errors.CheckErr(fmt.Errorf("at least one of --skip.config or --skip.binaries must be false"), formatType)
 	}
 
-	if config == "" && !skipConfig {
-		errors.CheckErr(fmt.Errorf("agent configuration file must be specified"), formatType)
-	}
 	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	planOut, _ := cmd.Flags().GetString("run.plan-out")
 
-	targetUrl := args[0]
+	hosts, fleet := getHosts(cmd, args, formatType)
+	for i := range hosts {
+		if hosts[i].AgentConfig == "" {
+			hosts[i].AgentConfig = config
+		}
+		if hosts[i].AgentConfig == "" && !skipConfig {
+			errors.CheckErr(fmt.Errorf("agent configuration file must be specified"), formatType)
+		}
+		if hosts[i].AlloyVersion == "" {
+			hosts[i].AlloyVersion = alloyVersion
+		}
+	}
 
-	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
-	a, err := agent.New(cmd.Context(), agent.Options{
-		Config:     config,
-		TargetURL:  targetUrl,
-		Format:     formatType,
-		DryRun:     dryRun,
-		CmdTimeout: time.Duration(timeout) * time.Second,
-	})
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
 	errors.CheckErr(err, formatType)
 
-	err = a.Update(skipConfig, skipBinaries, alloyVersion)
-	errors.CheckErr(err, formatType)
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
+
+	healthGate, _ := cmd.Flags().GetBool("health-gate")
+
+	rollHosts(cmd, hosts, fleet, formatType, "update", func(host inventory.Host, out io.Writer) error {
+		sshOpts, err := ssh.GetHostOptions(cmd, host.SSH)
+		if err != nil {
+			return err
+		}
+
+		a, err := agent.New(cmd.Context(), agent.Options{
+			Config:     host.AgentConfig,
+			TargetURL:  host.URL,
+			Format:     formatType,
+			DryRun:     dryRun,
+			PlanOut:    planOut,
+			CmdTimeout: time.Duration(timeout) * time.Second,
+			Escalation: escalationType,
+			Retry:      retry,
+			SSH:        sshOpts,
+			Output:     out,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := a.Update(skipConfig, skipBinaries, host.AlloyVersion); err != nil {
+			return err
+		}
+		if healthGate {
+			return a.Ready()
+		}
+
+		return nil
+	})
 }
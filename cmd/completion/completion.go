@@ -10,11 +10,14 @@ import (
 	"fmt"
 	"maps"
 	"os"
-	"os/user"
 	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/internal/config"
+	"github.com/tschaefer/finchctl/internal/inventory"
+	"github.com/tschaefer/finchctl/internal/service"
+	"github.com/tschaefer/finchctl/internal/target"
 )
 
 func CompleteRunFormat(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
@@ -48,17 +51,24 @@ func CompleteDashboardRole(cmd *cobra.Command, args []string, toComplete string)
 	return []string{"viewer", "operator", "admin"}, cobra.ShellCompDirectiveNoFileComp
 }
 
+func CompleteHostKeyPolicy(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return []cobra.Completion{"accept-new", "insecure", "strict"}, cobra.ShellCompDirectiveNoFileComp
+}
+
+func CompleteEscalation(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return []cobra.Completion{"doas", "none", "sudo", "sudo-password"}, cobra.ShellCompDirectiveNoFileComp
+}
+
 func CompleteHostName(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
 	if len(args) != 0 {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
-	curUser, err := user.Current()
+	knownHosts, err := target.KnownHostsFile()
 	if err != nil {
 		panic(err)
 	}
 
-	knownHosts := curUser.HomeDir + "/.ssh/known_hosts"
 	if _, err = os.Stat(knownHosts); errors.Is(err, os.ErrNotExist) {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
@@ -76,14 +86,51 @@ func CompleteHostName(cmd *cobra.Command, args []string, toComplete string) ([]c
 
 	hosts := map[string]int{}
 	for fileScanner.Scan() {
-		var host string
-		if _, err := fmt.Sscan(fileScanner.Text(), &host); err != nil {
+		var field string
+		if _, err := fmt.Sscan(fileScanner.Text(), &field); err != nil {
+			continue
+		}
+		if strings.HasPrefix(field, "@") || strings.HasPrefix(field, "#") {
 			continue
 		}
 
-		hosts[host] = 1
+		for _, host := range strings.Split(field, ",") {
+			if strings.HasPrefix(host, "|") {
+				continue
+			}
+			if strings.HasPrefix(host, "[") {
+				host = strings.Replace(strings.TrimPrefix(host, "["), "]", "", 1)
+			}
+			hosts[host] = 1
+		}
 	}
 	list := slices.Sorted(maps.Keys(hosts))
 
 	return list, cobra.ShellCompDirectiveNoFileComp
 }
+
+func CompleteInventoryGroup(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	file, _ := cmd.Flags().GetString("inventory")
+	if file == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	inv, err := inventory.Load(file)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return inv.Groups(), cobra.ShellCompDirectiveNoFileComp
+}
+
+func CompleteBackupComponent(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return []cobra.Completion{"all", "grafana", "logs", "metrics", "profiles"}, cobra.ShellCompDirectiveNoFileComp
+}
+
+func CompleteImageComponent(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	var completions []cobra.Completion
+	for _, component := range slices.Sorted(maps.Keys(service.DefaultImages)) {
+		completions = append(completions, component+"=")
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
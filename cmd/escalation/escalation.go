@@ -0,0 +1,30 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package escalation
+
+import (
+	"fmt"
+
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func GetEscalation(name string) (target.Escalation, error) {
+	var escalation target.Escalation
+	var err error
+	switch name {
+	case "sudo":
+		escalation = target.EscalationSudo
+	case "sudo-password":
+		escalation = target.EscalationSudoPassword
+	case "doas":
+		escalation = target.EscalationDoas
+	case "none":
+		escalation = target.EscalationNone
+	default:
+		err = fmt.Errorf("unknown privilege escalation %s", name)
+	}
+
+	return escalation, err
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/agent"
+	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/service"
 	"github.com/tschaefer/finchctl/internal/grpc"
 	"github.com/tschaefer/finchctl/internal/version"
@@ -65,6 +66,15 @@ func init() {
 
 	rootCmd.PersistentFlags().Bool("tls.skip-verify", false, "Skip TLS certificate verification (not recommended)")
 	rootCmd.PersistentFlags().Uint("run.cmd-timeout", 300, "timeout in seconds for SSH commands")
+	rootCmd.PersistentFlags().Uint("run.retries", 2, "number of retries for SSH commands failing due to connection errors, started commands only if safe to repeat, and transfers")
+	rootCmd.PersistentFlags().Uint("run.retry-backoff", 2, "initial delay in seconds between retries, doubled on each retry")
+	rootCmd.PersistentFlags().String("run.escalation", "sudo", "privilege escalation on the target (sudo, sudo-password, doas, none)")
+	rootCmd.PersistentFlags().String("ssh.host-key-policy", "accept-new", "SSH host key verification policy (strict, accept-new, insecure)")
+	rootCmd.PersistentFlags().String("ssh.identity", "", "path to SSH private key file (default: from ~/.ssh/config or ~/.ssh/id_*)")
+	rootCmd.PersistentFlags().String("ssh.jump", "", "comma separated list of jump hosts [user@]host[:port] (default: ProxyJump from ~/.ssh/config)")
+
+	_ = rootCmd.RegisterFlagCompletionFunc("run.escalation", completion.CompleteEscalation)
+	_ = rootCmd.RegisterFlagCompletionFunc("ssh.host-key-policy", completion.CompleteHostKeyPolicy)
 
 	rootCmd.AddCommand(agent.Cmd)
 	rootCmd.AddCommand(service.Cmd)
@@ -0,0 +1,75 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tschaefer/finchctl/cmd/completion"
+	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
+	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
+	"github.com/tschaefer/finchctl/internal/service"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+var backupCmd = &cobra.Command{
+	Use:               "backup [user@]host[:port]",
+	Short:             "Backup service on a remote host",
+	Args:              cobra.ExactArgs(1),
+	Run:               runBackupCmd,
+	ValidArgsFunction: completion.CompleteHostName,
+}
+
+func init() {
+	backupCmd.Flags().String("run.format", "progress", "output format")
+	backupCmd.Flags().Bool("run.dry-run", false, "do not backup, just print the commands that would be run")
+	backupCmd.Flags().String("output", "finch-backup.tar.zst", "path of the backup archive (.tar.zst, .tar.gz or .tar)")
+	backupCmd.Flags().StringSlice("components", nil, "data to backup besides the configuration (grafana, logs, metrics, profiles, all)")
+
+	_ = backupCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
+	_ = backupCmd.RegisterFlagCompletionFunc("components", completion.CompleteBackupComponent)
+}
+
+func runBackupCmd(cmd *cobra.Command, args []string) {
+	targetUrl := args[0]
+
+	formatName, _ := cmd.Flags().GetString("run.format")
+	formatType, err := format.GetRunFormat(formatName)
+	cobra.CheckErr(err)
+	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	output, _ := cmd.Flags().GetString("output")
+	components, _ := cmd.Flags().GetStringSlice("components")
+
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
+
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
+	s, err := service.New(cmd.Context(), service.Options{
+		TargetURL:  targetUrl,
+		Format:     formatType,
+		DryRun:     dryRun,
+		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
+	})
+	errors.CheckErr(err, formatType)
+
+	err = s.Backup(service.BackupOptions{
+		Output:     output,
+		Components: components,
+	})
+	errors.CheckErr(err, formatType)
+}
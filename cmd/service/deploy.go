@@ -13,7 +13,9 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
 	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
 	"github.com/tschaefer/finchctl/internal/service"
 	"github.com/tschaefer/finchctl/internal/target"
 )
@@ -29,6 +31,7 @@ var deployCmd = &cobra.Command{
 func init() {
 	deployCmd.Flags().String("run.format", "progress", "output format")
 	deployCmd.Flags().Bool("run.dry-run", false, "do not deploy, just print the commands that would be run")
+	deployCmd.Flags().String("run.plan-out", "", "write the commands of a dry run as shell script to the given file")
 	deployCmd.Flags().String("service.host", "", "service host (default: auto-detected from target URL)")
 	deployCmd.Flags().Bool("service.letsencrypt", false, "use Let's Encrypt for TLS certificate (default: false)")
 	deployCmd.Flags().String("service.letsencrypt.email", "", "email address for Let's Encrypt registration (required if --service.letsencrypt is true)")
@@ -36,7 +39,17 @@ func init() {
 	deployCmd.Flags().String("service.customtls.cert", "", "path to custom TLS certificate file (required if --service.customtls is true)")
 	deployCmd.Flags().String("service.customtls.key", "", "path to custom TLS key file (required if --service.customtls is true)")
 
+	deployCmd.Flags().String("retention.logs", "72h", "retention period of logs")
+	deployCmd.Flags().String("retention.metrics", "72h", "retention period of metrics")
+	deployCmd.Flags().String("retention.profiles", "72h", "retention period of profiles")
+
+	deployCmd.Flags().StringArray("image", nil, "image of a component as component=image, 'default' selects the image of the release")
+	deployCmd.Flags().String("images", "", "YAML file mapping components to images")
+	deployCmd.Flags().Bool("pin-digests", false, "pin the images by their digest")
+	deployCmd.Flags().Bool("offline", false, "do not access the internet from the target, the images are exported on this machine")
+
 	_ = deployCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
+	_ = deployCmd.RegisterFlagCompletionFunc("image", completion.CompleteImageComponent)
 }
 
 func runDeployCmd(cmd *cobra.Command, args []string) {
@@ -49,14 +62,34 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	errors.CheckErr(err, formatType)
 
 	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	planOut, _ := cmd.Flags().GetString("run.plan-out")
+	pinDigests, _ := cmd.Flags().GetBool("pin-digests")
+	offline, _ := cmd.Flags().GetBool("offline")
+	errors.CheckErr(offlineConflicts(cmd, offline), formatType)
+
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
 
 	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
 	s, err := service.New(cmd.Context(), service.Options{
 		Config:     config,
 		TargetURL:  targetUrl,
 		Format:     formatType,
 		DryRun:     dryRun,
+		PlanOut:    planOut,
 		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
+		PinDigests: pinDigests,
+		Offline:    offline,
 	})
 	errors.CheckErr(err, formatType)
 
@@ -68,7 +101,7 @@ func deployConfig(cmd *cobra.Command, args []string, formatType target.Format) (
 	config := &service.ServiceConfig{}
 	targetUrl := args[0]
 
-	if !strings.HasPrefix(targetUrl, "ssh://") {
+	if !strings.Contains(targetUrl, "://") {
 		targetUrl = "ssh://" + targetUrl
 	}
 	target, err := url.Parse(targetUrl)
@@ -94,5 +127,45 @@ func deployConfig(cmd *cobra.Command, args []string, formatType target.Format) (
 	config.CustomTLS.CertFilePath = customTLSCert
 	config.CustomTLS.KeyFilePath = customTLSKey
 
+	for flag, period := range map[string]*string{
+		"retention.logs":     &config.Retention.Logs,
+		"retention.metrics":  &config.Retention.Metrics,
+		"retention.profiles": &config.Retention.Profiles,
+	} {
+		*period, _ = cmd.Flags().GetString(flag)
+		if err := service.ValidateRetention(*period); err != nil {
+			return nil, fmt.Errorf("--%s: %w", flag, err)
+		}
+	}
+
+	imageSpecs, _ := cmd.Flags().GetStringArray("image")
+	imageFile, _ := cmd.Flags().GetString("images")
+	images, err := service.ParseImages(imageSpecs, imageFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) > 0 {
+		config.Images = images
+	}
+
 	return config, nil
 }
+
+// offlineConflicts returns an error if a flag needing internet on the target
+// or the plan of a dry run is combined with offline.
+func offlineConflicts(cmd *cobra.Command, offline bool) error {
+	if !offline {
+		return nil
+	}
+
+	for _, flag := range []string{"pin-digests", "service.letsencrypt"} {
+		if f := cmd.Flags().Lookup(flag); f != nil && f.Value.String() == "true" {
+			return fmt.Errorf("--%s cannot be used with --offline", flag)
+		}
+	}
+	if planOut, _ := cmd.Flags().GetString("run.plan-out"); planOut != "" {
+		return fmt.Errorf("--run.plan-out cannot be used with --offline, the images are not embedded")
+	}
+
+	return nil
+}
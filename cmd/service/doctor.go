@@ -16,6 +16,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
+	"github.com/tschaefer/finchctl/cmd/ssh"
 	"github.com/tschaefer/finchctl/internal/service"
 	"github.com/tschaefer/finchctl/internal/target"
 
@@ -45,13 +47,26 @@ func runDoctorCmd(cmd *cobra.Command, args []string) {
 	cfg, err := doctorConfig(cmd, args, target.FormatQuiet)
 	errors.CheckErr(err, target.FormatQuiet)
 
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, target.FormatQuiet)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, target.FormatQuiet)
+
 	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
 	s, err := service.New(cmd.Context(), service.Options{
 		Config:     cfg,
 		TargetURL:  targetUrl,
 		Format:     target.FormatQuiet,
 		DryRun:     false,
 		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
 	})
 	errors.CheckErr(err, target.FormatQuiet)
 
@@ -79,7 +94,7 @@ func doctorConfig(cmd *cobra.Command, args []string, formatType target.Format) (
 	config := &service.ServiceConfig{}
 	targetUrl := args[0]
 
-	if !strings.HasPrefix(targetUrl, "ssh://") {
+	if !strings.Contains(targetUrl, "://") {
 		targetUrl = "ssh://" + targetUrl
 	}
 	target, err := url.Parse(targetUrl)
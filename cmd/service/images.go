@@ -0,0 +1,79 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/tschaefer/finchctl/cmd/completion"
+	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
+	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
+	"github.com/tschaefer/finchctl/internal/service"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+var imagesCmd = &cobra.Command{
+	Use:               "images [user@]host[:port]",
+	Short:             "Show the images of the service components",
+	Args:              cobra.ExactArgs(1),
+	Run:               runImagesCmd,
+	ValidArgsFunction: completion.CompleteHostName,
+}
+
+func init() {
+	imagesCmd.Flags().Bool("output.json", false, "output in JSON format")
+}
+
+func runImagesCmd(cmd *cobra.Command, args []string) {
+	targetUrl := args[0]
+
+	formatType, err := format.GetRunFormat("quiet")
+	cobra.CheckErr(err)
+
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
+
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
+	s, err := service.New(cmd.Context(), service.Options{
+		TargetURL:  targetUrl,
+		Format:     formatType,
+		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
+	})
+	errors.CheckErr(err, formatType)
+
+	images, err := s.Images()
+	errors.CheckErr(err, formatType)
+
+	jsonOutput, _ := cmd.Flags().GetBool("output.json")
+	if jsonOutput {
+		out, err := json.MarshalIndent(images, "", "  ")
+		errors.CheckErr(err, formatType)
+		fmt.Println(string(out))
+		return
+	}
+
+	t := tablewriter.NewWriter(cmd.OutOrStdout())
+	t.Header([]string{"Component", "Image", "Source", "Running"})
+	for _, image := range images {
+		_ = t.Append([]string{image.Component, image.Image, image.Source, image.Running})
+	}
+	_ = t.Render()
+}
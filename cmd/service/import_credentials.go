@@ -0,0 +1,31 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/internal/config"
+)
+
+var importCredentialsCmd = &cobra.Command{
+	Use:   "import-credentials file",
+	Short: "Import the client credentials written with a deployment plan",
+	Args:  cobra.ExactArgs(1),
+	Run:   runImportCredentialsCmd,
+}
+
+func runImportCredentialsCmd(cmd *cobra.Command, args []string) {
+	formatType, err := format.GetRunFormat("quiet")
+	cobra.CheckErr(err)
+
+	name, err := config.ImportStack(args[0])
+	errors.CheckErr(err, formatType)
+
+	fmt.Printf("Imported client credentials of stack %s.\n", name)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tschaefer/finchctl/cmd/completion"
+	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
+	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
+	"github.com/tschaefer/finchctl/internal/service"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+var logsCmd = &cobra.Command{
+	Use:               "logs [user@]host[:port] [component...]",
+	Short:             "Show the logs of the service components",
+	Args:              cobra.MinimumNArgs(1),
+	Run:               runLogsCmd,
+	ValidArgsFunction: completion.CompleteHostName,
+}
+
+func init() {
+	logsCmd.Flags().Bool("follow", false, "follow the log output")
+	logsCmd.Flags().String("since", "", "show logs since a timestamp or relative duration, e.g. 30m")
+	logsCmd.Flags().String("tail", "all", "number of lines to show from the end of the logs")
+}
+
+func runLogsCmd(cmd *cobra.Command, args []string) {
+	targetUrl := args[0]
+
+	formatType, err := format.GetRunFormat("quiet")
+	cobra.CheckErr(err)
+
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
+
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
+	s, err := service.New(cmd.Context(), service.Options{
+		TargetURL:  targetUrl,
+		Format:     formatType,
+		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
+	})
+	errors.CheckErr(err, formatType)
+
+	follow, _ := cmd.Flags().GetBool("follow")
+	since, _ := cmd.Flags().GetString("since")
+	tail, _ := cmd.Flags().GetString("tail")
+	err = s.Logs(service.LogsOptions{
+		Components: args[1:],
+		Follow:     follow,
+		Since:      since,
+		Tail:       tail,
+	}, cmd.OutOrStdout())
+	errors.CheckErr(err, formatType)
+}
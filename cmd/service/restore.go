@@ -0,0 +1,69 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tschaefer/finchctl/cmd/completion"
+	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
+	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
+	"github.com/tschaefer/finchctl/internal/service"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:               "restore [user@]host[:port]",
+	Short:             "Restore service on a remote host from a backup",
+	Args:              cobra.ExactArgs(1),
+	Run:               runRestoreCmd,
+	ValidArgsFunction: completion.CompleteHostName,
+}
+
+func init() {
+	restoreCmd.Flags().String("run.format", "progress", "output format")
+	restoreCmd.Flags().Bool("run.dry-run", false, "do not restore, just print the commands that would be run")
+	restoreCmd.Flags().String("from", "finch-backup.tar.zst", "path of the backup archive (.tar.zst, .tar.gz or .tar)")
+
+	_ = restoreCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
+}
+
+func runRestoreCmd(cmd *cobra.Command, args []string) {
+	targetUrl := args[0]
+
+	formatName, _ := cmd.Flags().GetString("run.format")
+	formatType, err := format.GetRunFormat(formatName)
+	cobra.CheckErr(err)
+	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	from, _ := cmd.Flags().GetString("from")
+
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
+
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
+	s, err := service.New(cmd.Context(), service.Options{
+		TargetURL:  targetUrl,
+		Format:     formatType,
+		DryRun:     dryRun,
+		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
+	})
+	errors.CheckErr(err, formatType)
+
+	err = s.Restore(service.RestoreOptions{From: from})
+	errors.CheckErr(err, formatType)
+}
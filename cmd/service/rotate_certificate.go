@@ -10,8 +10,11 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
 	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
 	"github.com/tschaefer/finchctl/internal/service"
+	"github.com/tschaefer/finchctl/internal/target"
 )
 
 var rotateCertificateCmd = &cobra.Command{
@@ -25,6 +28,7 @@ var rotateCertificateCmd = &cobra.Command{
 func init() {
 	rotateCertificateCmd.Flags().String("run.format", "progress", "output format")
 	rotateCertificateCmd.Flags().Bool("run.dry-run", false, "do not rotate certificates, just print the commands that would be run")
+	rotateCertificateCmd.Flags().String("run.plan-out", "", "write the commands of a dry run as shell script to the given file")
 
 	_ = rotateCertificateCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
 }
@@ -36,13 +40,28 @@ func runRotateCertificateCmd(cmd *cobra.Command, args []string) {
 	formatType, err := format.GetRunFormat(formatName)
 	cobra.CheckErr(err)
 	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	planOut, _ := cmd.Flags().GetString("run.plan-out")
+
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
 
 	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
 	s, err := service.New(cmd.Context(), service.Options{
 		TargetURL:  targetUrl,
 		Format:     formatType,
 		DryRun:     dryRun,
+		PlanOut:    planOut,
 		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
 	})
 	errors.CheckErr(err, formatType)
 
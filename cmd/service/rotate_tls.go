@@ -0,0 +1,83 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tschaefer/finchctl/cmd/completion"
+	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
+	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
+	"github.com/tschaefer/finchctl/internal/service"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+var rotateTLSCmd = &cobra.Command{
+	Use:               "rotate-tls [user@]host[:port]",
+	Short:             "Rotate the custom TLS certificate of a service on a remote host",
+	Args:              cobra.ExactArgs(1),
+	Run:               runRotateTLSCmd,
+	ValidArgsFunction: completion.CompleteHostName,
+}
+
+func init() {
+	rotateTLSCmd.Flags().String("cert", "", "path to the TLS certificate file")
+	rotateTLSCmd.Flags().String("key", "", "path to the TLS key file")
+	rotateTLSCmd.Flags().String("run.format", "progress", "output format")
+	rotateTLSCmd.Flags().Bool("run.dry-run", false, "do not rotate the certificate, just print the commands that would be run")
+	rotateTLSCmd.Flags().String("run.plan-out", "", "write the commands of a dry run as shell script to the given file")
+
+	_ = rotateTLSCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
+}
+
+func runRotateTLSCmd(cmd *cobra.Command, args []string) {
+	targetUrl := args[0]
+
+	formatName, _ := cmd.Flags().GetString("run.format")
+	formatType, err := format.GetRunFormat(formatName)
+	cobra.CheckErr(err)
+	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	planOut, _ := cmd.Flags().GetString("run.plan-out")
+	if planOut != "" {
+		errors.CheckErr("--run.plan-out cannot be used with rotate-tls, the new certificate is verified and rolled back while running", formatType)
+	}
+	certFile, _ := cmd.Flags().GetString("cert")
+	keyFile, _ := cmd.Flags().GetString("key")
+	if certFile == "" || keyFile == "" {
+		errors.CheckErr("certificate and key files are required", formatType)
+	}
+
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
+
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
+	s, err := service.New(cmd.Context(), service.Options{
+		TargetURL:  targetUrl,
+		Format:     formatType,
+		DryRun:     dryRun,
+		PlanOut:    planOut,
+		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
+	})
+	errors.CheckErr(err, formatType)
+
+	err = s.RotateTLS(service.RotateTLSOptions{
+		CertFilePath: certFile,
+		KeyFilePath:  keyFile,
+	})
+	errors.CheckErr(err, formatType)
+}
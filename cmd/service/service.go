@@ -19,7 +19,14 @@ func init() {
 	Cmd.AddCommand(dashboardCmd)
 	Cmd.AddCommand(rotateSecretCmd)
 	Cmd.AddCommand(rotateCertificateCmd)
+	Cmd.AddCommand(rotateTLSCmd)
+	Cmd.AddCommand(importCredentialsCmd)
 	Cmd.AddCommand(registerCmd)
 	Cmd.AddCommand(deregisterCmd)
 	Cmd.AddCommand(doctorCmd)
+	Cmd.AddCommand(backupCmd)
+	Cmd.AddCommand(restoreCmd)
+	Cmd.AddCommand(imagesCmd)
+	Cmd.AddCommand(statusCmd)
+	Cmd.AddCommand(logsCmd)
 }
@@ -0,0 +1,84 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/tschaefer/finchctl/cmd/completion"
+	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
+	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
+	"github.com/tschaefer/finchctl/internal/service"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+var statusCmd = &cobra.Command{
+	Use:               "status [user@]host[:port]",
+	Short:             "Show the status of the service containers",
+	Args:              cobra.ExactArgs(1),
+	Run:               runStatusCmd,
+	ValidArgsFunction: completion.CompleteHostName,
+}
+
+func init() {
+	statusCmd.Flags().Bool("output.json", false, "output in JSON format")
+}
+
+func runStatusCmd(cmd *cobra.Command, args []string) {
+	targetUrl := args[0]
+
+	formatType, err := format.GetRunFormat("quiet")
+	cobra.CheckErr(err)
+
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
+
+	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
+	s, err := service.New(cmd.Context(), service.Options{
+		TargetURL:  targetUrl,
+		Format:     formatType,
+		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
+	})
+	errors.CheckErr(err, formatType)
+
+	list, ok, err := s.Status()
+	errors.CheckErr(err, formatType)
+
+	jsonOutput, _ := cmd.Flags().GetBool("output.json")
+	if jsonOutput {
+		out, err := json.MarshalIndent(list, "", "  ")
+		errors.CheckErr(err, formatType)
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+	} else {
+		t := tablewriter.NewWriter(cmd.OutOrStdout())
+		t.Header([]string{"Service", "State", "Health", "Uptime", "Restarts", "Image"})
+		for _, item := range list {
+			_ = t.Append([]string{item.Service, item.State, item.Health, item.Uptime, strconv.Itoa(item.Restarts), item.Image})
+		}
+		_ = t.Render()
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
 	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
 	"github.com/tschaefer/finchctl/internal/service"
 	"github.com/tschaefer/finchctl/internal/target"
 )
@@ -29,6 +31,7 @@ var teardownCmd = &cobra.Command{
 func init() {
 	teardownCmd.Flags().String("run.format", "progress", "output format")
 	teardownCmd.Flags().Bool("run.dry-run", false, "do not deploy, just print the commands that would be run")
+	teardownCmd.Flags().String("run.plan-out", "", "write the commands of a dry run as shell script to the given file")
 	teardownCmd.Flags().String("service.host", "", "service host (default: auto-detected from target URL)")
 
 	_ = teardownCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
@@ -42,17 +45,32 @@ func runTeardownCmd(cmd *cobra.Command, args []string) {
 	cobra.CheckErr(err)
 
 	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	planOut, _ := cmd.Flags().GetString("run.plan-out")
 
 	cfg, err := teardownConfig(cmd, args, formatType)
 	errors.CheckErr(err, formatType)
 
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
+
 	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
 	s, err := service.New(cmd.Context(), service.Options{
 		Config:     cfg,
 		TargetURL:  targetUrl,
 		Format:     formatType,
 		DryRun:     dryRun,
+		PlanOut:    planOut,
 		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
 	})
 	errors.CheckErr(err, formatType)
 
@@ -64,7 +82,7 @@ func teardownConfig(cmd *cobra.Command, args []string, formatType target.Format)
 	config := &service.ServiceConfig{}
 	targetUrl := args[0]
 
-	if !strings.HasPrefix(targetUrl, "ssh://") {
+	if !strings.Contains(targetUrl, "://") {
 		targetUrl = "ssh://" + targetUrl
 	}
 	target, err := url.Parse(targetUrl)
@@ -10,8 +10,11 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tschaefer/finchctl/cmd/completion"
 	"github.com/tschaefer/finchctl/cmd/errors"
+	"github.com/tschaefer/finchctl/cmd/escalation"
 	"github.com/tschaefer/finchctl/cmd/format"
+	"github.com/tschaefer/finchctl/cmd/ssh"
 	"github.com/tschaefer/finchctl/internal/service"
+	"github.com/tschaefer/finchctl/internal/target"
 )
 
 var updateCmd = &cobra.Command{
@@ -25,11 +28,18 @@ var updateCmd = &cobra.Command{
 func init() {
 	updateCmd.Flags().String("run.format", "progress", "output format")
 	updateCmd.Flags().Bool("run.dry-run", false, "do not update, just print the commands that would be run")
+	updateCmd.Flags().String("run.plan-out", "", "write the commands of a dry run as shell script to the given file")
 	updateCmd.Flags().Bool("service.customtls", false, "use custom TLS certificate (default: false)")
 	updateCmd.Flags().String("service.customtls.cert", "", "path to custom TLS certificate file (required if --service.customtls is true)")
 	updateCmd.Flags().String("service.customtls.key", "", "path to custom TLS key file (required if --service.customtls is true)")
 
+	updateCmd.Flags().StringArray("image", nil, "image of a component as component=image, 'default' selects the image of the release")
+	updateCmd.Flags().String("images", "", "YAML file mapping components to images")
+	updateCmd.Flags().Bool("pin-digests", false, "pin the images by their digest")
+	updateCmd.Flags().Bool("offline", false, "do not access the internet from the target, the images are exported on this machine")
+
 	_ = updateCmd.RegisterFlagCompletionFunc("run.format", completion.CompleteRunFormat)
+	_ = updateCmd.RegisterFlagCompletionFunc("image", completion.CompleteImageComponent)
 }
 
 func runUpdateCmd(cmd *cobra.Command, args []string) {
@@ -39,19 +49,44 @@ func runUpdateCmd(cmd *cobra.Command, args []string) {
 	formatType, err := format.GetRunFormat(formatName)
 	cobra.CheckErr(err)
 	dryRun, _ := cmd.Flags().GetBool("run.dry-run")
+	planOut, _ := cmd.Flags().GetString("run.plan-out")
 
 	config := &service.ServiceConfig{}
 	config.CustomTLS.Enabled, _ = cmd.Flags().GetBool("service.customtls")
 	config.CustomTLS.CertFilePath, _ = cmd.Flags().GetString("service.customtls.cert")
 	config.CustomTLS.KeyFilePath, _ = cmd.Flags().GetString("service.customtls.key")
 
+	imageSpecs, _ := cmd.Flags().GetStringArray("image")
+	imageFile, _ := cmd.Flags().GetString("images")
+	config.Images, err = service.ParseImages(imageSpecs, imageFile)
+	errors.CheckErr(err, formatType)
+	pinDigests, _ := cmd.Flags().GetBool("pin-digests")
+	offline, _ := cmd.Flags().GetBool("offline")
+	errors.CheckErr(offlineConflicts(cmd, offline), formatType)
+
+	sshOpts, err := ssh.GetOptions(cmd)
+	errors.CheckErr(err, formatType)
+
+	escalationName, _ := cmd.Flags().GetString("run.escalation")
+	escalationType, err := escalation.GetEscalation(escalationName)
+	errors.CheckErr(err, formatType)
+
 	timeout, _ := cmd.Flags().GetUint("run.cmd-timeout")
+	retries, _ := cmd.Flags().GetUint("run.retries")
+	backoff, _ := cmd.Flags().GetUint("run.retry-backoff")
+	retry := target.RetryOptions{Retries: retries, Backoff: time.Duration(backoff) * time.Second}
 	s, err := service.New(cmd.Context(), service.Options{
 		TargetURL:  targetUrl,
 		Format:     formatType,
 		DryRun:     dryRun,
+		PlanOut:    planOut,
 		CmdTimeout: time.Duration(timeout) * time.Second,
+		Escalation: escalationType,
+		Retry:      retry,
+		SSH:        sshOpts,
 		Config:     config,
+		PinDigests: pinDigests,
+		Offline:    offline,
 	})
 	errors.CheckErr(err, formatType)
 
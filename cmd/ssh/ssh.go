@@ -0,0 +1,69 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package ssh
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tschaefer/finchctl/internal/inventory"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func GetHostKeyPolicy(name string) (target.HostKeyPolicy, error) {
+	var policy target.HostKeyPolicy
+	var err error
+	switch name {
+	case "accept-new":
+		policy = target.HostKeyAcceptNew
+	case "strict":
+		policy = target.HostKeyStrict
+	case "insecure":
+		policy = target.HostKeyInsecure
+	default:
+		err = fmt.Errorf("unknown host key policy %s", name)
+	}
+
+	return policy, err
+}
+
+func GetOptions(cmd *cobra.Command) (target.SSHOptions, error) {
+	var opts target.SSHOptions
+
+	policyName, _ := cmd.Flags().GetString("ssh.host-key-policy")
+	policy, err := GetHostKeyPolicy(policyName)
+	if err != nil {
+		return opts, err
+	}
+	opts.HostKeyPolicy = policy
+	opts.IdentityFile, _ = cmd.Flags().GetString("ssh.identity")
+	opts.Jump, _ = cmd.Flags().GetString("ssh.jump")
+
+	return opts, nil
+}
+
+// GetHostOptions returns the SSH options of the command, overridden by the
+// settings of an inventory host.
+func GetHostOptions(cmd *cobra.Command, host inventory.SSH) (target.SSHOptions, error) {
+	opts, err := GetOptions(cmd)
+	if err != nil {
+		return opts, err
+	}
+
+	if host.HostKeyPolicy != "" {
+		opts.HostKeyPolicy, err = GetHostKeyPolicy(host.HostKeyPolicy)
+		if err != nil {
+			return opts, err
+		}
+	}
+	if host.Identity != "" {
+		opts.IdentityFile = host.Identity
+	}
+	if host.Jump != "" {
+		opts.Jump = host.Jump
+	}
+
+	return opts, nil
+}
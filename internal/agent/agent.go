@@ -6,6 +6,7 @@ package agent
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/tschaefer/finchctl/internal/target"
@@ -14,10 +15,15 @@ import (
 type Agent struct {
 	ctx        context.Context
 	target     target.Target
+	host       string
+	tracker    *target.Tracker
+	out        io.Writer
 	config     string
 	format     target.Format
 	dryRun     bool
+	planned    bool
 	cmdTimeout time.Duration
+	escalation target.Escalation
 }
 
 type Options struct {
@@ -25,14 +31,24 @@ type Options struct {
 	TargetURL  string
 	Format     target.Format
 	DryRun     bool
+	PlanOut    string
 	CmdTimeout time.Duration
+	Escalation target.Escalation
+	Retry      target.RetryOptions
+	SSH        target.SSHOptions
+	Output     io.Writer
 }
 
 func New(ctx context.Context, opts Options) (*Agent, error) {
 	t, err := target.New(opts.TargetURL, target.Options{
 		Format:     opts.Format,
-		DryRun:     opts.DryRun,
+		DryRun:     opts.DryRun || opts.PlanOut != "",
+		PlanOut:    opts.PlanOut,
 		CmdTimeout: opts.CmdTimeout,
+		Escalation: opts.Escalation,
+		Retry:      opts.Retry,
+		SSH:        opts.SSH,
+		Output:     opts.Output,
 	})
 	if err != nil {
 		return nil, err
@@ -41,10 +57,14 @@ func New(ctx context.Context, opts Options) (*Agent, error) {
 	return &Agent{
 		ctx:        ctx,
 		target:     t,
+		host:       opts.TargetURL,
+		out:        opts.Output,
 		config:     opts.Config,
 		format:     opts.Format,
-		dryRun:     opts.DryRun,
+		dryRun:     opts.DryRun || opts.PlanOut != "",
+		planned:    opts.PlanOut != "",
 		cmdTimeout: opts.CmdTimeout,
+		escalation: opts.Escalation,
 	}, nil
 }
 
@@ -71,19 +91,24 @@ func (a *Agent) Teardown() error {
 	return nil
 }
 
-func (a *Agent) Deploy(version string) error {
+func (a *Agent) Deploy(version string) (err error) {
+	a.tracker = target.NewTracker("agent.deploy", a.host, a.format, a.out)
 	defer func() {
 		if a.format == target.FormatProgress {
 			println()
 		}
+		a.tracker.Summary(err)
 	}()
 
 	if err := a.requirementsAgent(); err != nil {
 		return err
 	}
 
-	machine, err := a.machineInfo()
-	if err != nil {
+	var machine *MachineInfo
+	if err := a.tracker.Step("machine.info", func() (err error) {
+		machine, err = a.machineInfo()
+		return err
+	}); err != nil {
 		return err
 	}
 
@@ -110,25 +135,35 @@ func (a *Agent) Config(service, resourceID string) ([]byte, error) {
 	return a.configAgent(service, resourceID)
 }
 
-func (a *Agent) Update(skipConfig bool, skipBinaries bool, version string) error {
+func (a *Agent) Update(skipConfig bool, skipBinaries bool, version string) (err error) {
+	a.tracker = target.NewTracker("agent.update", a.host, a.format, a.out)
 	defer func() {
 		if a.format == target.FormatProgress {
 			println()
 		}
+		a.tracker.Summary(err)
 	}()
 
 	if err := a.requirementsAgent(); err != nil {
 		return convertError(err, &UpdateAgentError{})
 	}
 
-	machine, err := a.machineInfo()
-	if err != nil {
+	var machine *MachineInfo
+	if err := a.tracker.Step("machine.info", func() (err error) {
+		machine, err = a.machineInfo()
+		return err
+	}); err != nil {
 		return err
 	}
 
 	return a.updateAgent(machine, skipConfig, skipBinaries, version)
 }
 
+// Ready waits until Alloy reports ready on its HTTP port.
+func (a *Agent) Ready() error {
+	return a.readyAgent()
+}
+
 func (a *Agent) Describe(service, resourceID string) (*DescribeData, error) {
 	return a.describeAgent(service, resourceID)
 }
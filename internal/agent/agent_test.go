@@ -19,6 +19,8 @@ import (
 type track struct {
 	Timestamp string `json:"timestamp"`
 	Message   string `json:"message"`
+	Event     string `json:"event"`
+	Step      string `json:"step"`
 }
 
 func Test_Deploy(t *testing.T) {
@@ -61,6 +63,11 @@ func Test_Deploy(t *testing.T) {
 	var track track
 	err = json.Unmarshal([]byte(tracks[0]), &track)
 	assert.NoError(t, err, "unmarshal json output")
+	assert.Equal(t, "step_start", track.Event, "first log line event")
+	assert.Equal(t, "requirements.has-sudo", track.Step, "first log line step")
+
+	err = json.Unmarshal([]byte(tracks[1]), &track)
+	assert.NoError(t, err, "unmarshal json output")
 
 	assert.Regexp(t, "Running 'command -v sudo' as .+@localhost", track.Message, "first log line message")
 	assert.NotEmpty(t, track.Timestamp, "first log line timestamp")
@@ -153,11 +160,97 @@ func Test_Update(t *testing.T) {
 	var track track
 	err = json.Unmarshal([]byte(tracks[0]), &track)
 	assert.NoError(t, err, "unmarshal json output")
+	assert.Equal(t, "step_start", track.Event, "first log line event")
+	assert.Equal(t, "requirements.has-sudo", track.Step, "first log line step")
+
+	err = json.Unmarshal([]byte(tracks[1]), &track)
+	assert.NoError(t, err, "unmarshal json output")
 
 	assert.Regexp(t, "Running 'command -v sudo' as .+@localhost", track.Message, "first log line message")
 	assert.NotEmpty(t, track.Timestamp, "first log line timestamp")
 }
 
+func replay(t *testing.T, a *Agent, fixture string) {
+	r, err := target.NewReplay("testdata/"+fixture, target.Options{Format: target.FormatQuiet})
+	assert.NoError(t, err, "load fixture")
+	a.target = r
+}
+
+func Test_UpdateReplay(t *testing.T) {
+	a, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create agent")
+
+	replay(t, a, "update.jsonl")
+	err = a.Update(true, false, "v1.9.0")
+	assert.NoError(t, err, "update agent")
+
+	replay(t, a, "update-unsupported.jsonl")
+	err = a.Update(true, false, "v1.9.0")
+	assert.Error(t, err, "update agent")
+	assert.IsType(t, &DeployAgentError{}, err, "error type")
+	assert.ErrorContains(t, err, "unsupported target architecture riscv64", "error message")
+}
+
+func Test_DoctorReplay(t *testing.T) {
+	a, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create agent")
+
+	replay(t, a, "doctor.jsonl")
+	list, ok := a.Doctor(true, true)
+	assert.False(t, ok, "doctor result")
+
+	status := map[string]bool{}
+	for _, h := range *list {
+		status[h.Requirement] = h.Ok
+	}
+	assert.Equal(t, map[string]bool{
+		"os":                   true,
+		"sudo":                 true,
+		"superuser permission": true,
+		"curl":                 true,
+		"unzip":                true,
+		"GitHub connection":    true,
+		"port 12345":           true,
+		"port 3100":            false,
+		"port 9091":            true,
+		"port 4040":            true,
+	}, status, "health checks")
+}
+
+func Test_ReadyReplay(t *testing.T) {
+	interval := readyInterval
+	readyInterval = 10 * time.Millisecond
+	defer func() { readyInterval = interval }()
+
+	a, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create agent")
+
+	replay(t, a, "ready.jsonl")
+	err = a.Ready()
+	assert.NoError(t, err, "agent ready on second check")
+
+	maxWait := readyMaxWait
+	readyMaxWait = 0
+	defer func() { readyMaxWait = maxWait }()
+
+	replay(t, a, "ready.jsonl")
+	err = a.Ready()
+	assert.IsType(t, &ReadyAgentError{}, err, "error type")
+	assert.ErrorContains(t, err, "Alloy not ready on port 12345", "error message")
+}
+
 func capture(f func()) string {
 	originalStdout := os.Stdout
 
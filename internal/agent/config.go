@@ -18,7 +18,7 @@ func (a *Agent) configAgent(service, rid string) ([]byte, error) {
 
 	ctx, client, err := grpc.NewClient(ctx, service, api.NewAgentServiceClient)
 	if err != nil {
-		return nil, &ConfigAgentError{Message: err.Error(), Reason: ""}
+		return nil, &ConfigAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = client.Close()
@@ -28,7 +28,7 @@ func (a *Agent) configAgent(service, rid string) ([]byte, error) {
 		Rid: rid,
 	})
 	if err != nil {
-		return nil, &ConfigAgentError{Message: err.Error(), Reason: ""}
+		return nil, &ConfigAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	return cfg.Config, nil
@@ -34,7 +34,7 @@ func (a *Agent) __deployMakeDirHierarchy() error {
 	for _, dir := range directories {
 		out, err := a.target.Run(a.ctx, "sudo mkdir -p "+dir)
 		if err != nil {
-			return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+			return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 		}
 	}
 
@@ -43,7 +43,7 @@ func (a *Agent) __deployMakeDirHierarchy() error {
 
 func (a *Agent) __deployCopyConfigFile() error {
 	if out, err := a.target.Copy(a.ctx, a.config, "/etc/alloy/alloy.config", "400", "0:0"); err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -54,22 +54,22 @@ func (a *Agent) __deployCopySystemdServiceUnit() error {
 
 	content, err := fs.ReadFile(Assets, "alloy.service")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: ""}
+		return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	f, err := os.CreateTemp("", "alloy.service")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: ""}
+		return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = os.Remove(f.Name())
 	}()
 	if _, err := f.Write(content); err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: ""}
+		return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	if out, err := a.target.Copy(a.ctx, f.Name(), dest, "444", "0:0"); err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -80,27 +80,27 @@ func (a *Agent) __deployCopyRcServiceFile() error {
 
 	content, err := fs.ReadFile(Assets, "alloy.rc")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: ""}
+		return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	f, err := os.CreateTemp("", "alloy.rc")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: ""}
+		return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = os.Remove(f.Name())
 	}()
 	if _, err := f.Write(content); err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: ""}
+		return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	if out, err := a.target.Copy(a.ctx, f.Name(), dest, "444", "0:0"); err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err := a.target.Run(a.ctx, "sudo chmod +x "+dest)
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -111,22 +111,22 @@ func (a *Agent) __deployCopyLaunchdServiceFile() error {
 
 	content, err := fs.ReadFile(Assets, "com.github.tschaefer.finch.agent.plist")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: ""}
+		return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	f, err := os.CreateTemp("", "com.github.tschaefer.finch.agent.plist")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: ""}
+		return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = os.Remove(f.Name())
 	}()
 	if _, err := f.Write(content); err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: ""}
+		return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	if out, err := a.target.Copy(a.ctx, f.Name(), dest, "444", "0:0"); err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -148,7 +148,7 @@ func (a *Agent) __deployDownloadRelease(release string, version string, tmpdir s
 
 	out, err := os.Create(tmpfile)
 	if err != nil {
-		return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+		return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = out.Close()
@@ -159,13 +159,13 @@ func (a *Agent) __deployDownloadRelease(release string, version string, tmpdir s
 
 	req, err := http.NewRequestWithContext(opCtx, "GET", url, nil)
 	if err != nil {
-		return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+		return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+		return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -177,7 +177,7 @@ func (a *Agent) __deployDownloadRelease(release string, version string, tmpdir s
 
 	_, err = io.Copy(out, resp.Body)
 	if err != nil {
-		return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+		return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	return tmpfile, nil
@@ -195,7 +195,7 @@ func (a *Agent) __deployUnzipRelease(release string, file string) (string, error
 
 	archive, err := zip.OpenReader(file)
 	if err != nil {
-		return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+		return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = archive.Close()
@@ -203,7 +203,7 @@ func (a *Agent) __deployUnzipRelease(release string, file string) (string, error
 
 	binary, err := os.Create(tmpfile)
 	if err != nil {
-		return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+		return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = binary.Close()
@@ -215,14 +215,14 @@ func (a *Agent) __deployUnzipRelease(release string, file string) (string, error
 		}
 		data, err := part.Open()
 		if err != nil {
-			return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+			return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 		}
 		defer func() {
 			_ = data.Close()
 		}()
 
 		if _, err := io.Copy(binary, data); err != nil {
-			return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+			return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 		}
 
 		break
@@ -230,14 +230,14 @@ func (a *Agent) __deployUnzipRelease(release string, file string) (string, error
 
 	info, err := binary.Stat()
 	if err != nil {
-		return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+		return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	if info.Size() == 0 {
 		return "", &DeployAgentError{Message: "Downloaded binary is empty", Reason: ""}
 	}
 
 	if err := binary.Chmod(0755); err != nil {
-		return "", &DeployAgentError{Message: err.Error(), Reason: ""}
+		return "", &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	return binary.Name(), nil
@@ -249,13 +249,13 @@ func (a *Agent) __deployInstallBinary(binary string, machine *MachineInfo) error
 		binPath = "/usr/local/bin/alloy"
 		out, err := a.target.Run(a.ctx, "sudo mkdir -p "+path.Dir(binPath))
 		if err != nil {
-			return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+			return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 		}
 	}
 
 	out, err := a.target.Copy(a.ctx, binary, binPath, "755", "0:0")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -264,7 +264,7 @@ func (a *Agent) __deployInstallBinary(binary string, machine *MachineInfo) error
 func (a *Agent) __deployEnableSystemdService() error {
 	out, err := a.target.Run(a.ctx, "sudo systemctl enable --now alloy")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 	return nil
 }
@@ -272,12 +272,12 @@ func (a *Agent) __deployEnableSystemdService() error {
 func (a *Agent) __deployEnableRcService() error {
 	out, err := a.target.Run(a.ctx, "sudo sysrc alloy_enable=YES")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = a.target.Run(a.ctx, "sudo service alloy start")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -286,25 +286,25 @@ func (a *Agent) __deployEnableRcService() error {
 func (a *Agent) __deployEnableLaunchdService() error {
 	out, err := a.target.Run(a.ctx, "sudo launchctl bootstrap system /Library/LaunchDaemons/com.github.tschaefer.finch.agent.plist")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = a.target.Run(a.ctx, "sudo launchctl kickstart -k system/com.github.tschaefer.finch.agent")
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
 }
 
 func (a *Agent) __deployDownloadReleaseOnTarget(release string, version string) (string, error) {
-	raw, err := a.target.Run(a.ctx, "mktemp -p /tmp -d finch-XXXXXX")
+	raw, err := a.target.Run(target.Idempotent(a.ctx), "mktemp -p /tmp -d finch-XXXXXX")
 	if err != nil {
-		return "", &DeployAgentError{Message: err.Error(), Reason: string(raw)}
+		return "", &DeployAgentError{Message: err.Error(), Reason: string(raw), Err: err}
 	}
 	tmpdir := strings.TrimSpace(string(raw))
 	if a.dryRun {
-		tmpdir = "/tmp/finchctl-dry-run"
+		tmpdir = target.DryRunTmpDir
 	}
 
 	var url string
@@ -315,10 +315,10 @@ func (a *Agent) __deployDownloadReleaseOnTarget(release string, version string)
 	}
 	tmpfile := filepath.Join(tmpdir, release+"-"+time.Now().Format("19800212015200")+".zip")
 
-	out, err := a.target.Run(a.ctx, "curl -sfL -o "+tmpfile+" "+url)
+	out, err := a.target.Run(target.Idempotent(a.ctx), "curl -sfL -o "+tmpfile+" "+url)
 	if err != nil {
 		_, _ = a.target.Run(a.ctx, "rm -rf "+tmpdir)
-		return "", &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return "", &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return tmpfile, nil
@@ -328,7 +328,7 @@ func (a *Agent) __deployInstallBinaryOnTarget(zipfile string, machine *MachineIn
 	tmpdir := filepath.Dir(zipfile)
 	out, err := a.target.Run(a.ctx, "unzip -d "+tmpdir+" "+zipfile)
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	binPath := "/usr/bin/alloy"
@@ -336,13 +336,13 @@ func (a *Agent) __deployInstallBinaryOnTarget(zipfile string, machine *MachineIn
 		binPath = "/usr/local/bin/alloy"
 		out, err := a.target.Run(a.ctx, "sudo mkdir -p "+path.Dir(binPath))
 		if err != nil {
-			return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+			return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 		}
 	}
 	installCmd := fmt.Sprintf("sudo install -m 755 -o root -g root %s %s", filepath.Join(tmpdir, release), binPath)
-	out, err = a.target.Run(a.ctx, installCmd)
+	out, err = a.target.Run(target.Idempotent(a.ctx), installCmd)
 	if err != nil {
-		return &DeployAgentError{Message: err.Error(), Reason: string(out)}
+		return &DeployAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -355,76 +355,89 @@ func (a *Agent) __helperPrintProgress(message string) {
 		username = user.Username
 	}
 
-	target.PrintProgress(fmt.Sprintf("%s as %s@localhost", message, username), a.format)
+	target.FprintProgress(a.out, fmt.Sprintf("%s as %s@localhost", message, username), a.format)
 }
 
 func (a *Agent) deployAgent(machine *MachineInfo, alloyVersion string) error {
-	if err := a.__deployMakeDirHierarchy(); err != nil {
+	if err := a.tracker.Step("deploy.make-dir-hierarchy", a.__deployMakeDirHierarchy); err != nil {
 		return err
 	}
 
-	if err := a.__deployCopyConfigFile(); err != nil {
+	if err := a.tracker.Step("deploy.copy-config-file", a.__deployCopyConfigFile); err != nil {
 		return err
 	}
 
 	release := fmt.Sprintf("alloy-%s-%s", machine.Kernel, machine.Arch)
 
 	if a.additionsAgent() {
-		zipfile, err := a.__deployDownloadReleaseOnTarget(release, alloyVersion)
-		if err != nil {
+		var zipfile string
+		if err := a.tracker.Step("deploy.download-release-on-target", func() (err error) {
+			zipfile, err = a.__deployDownloadReleaseOnTarget(release, alloyVersion)
+			return err
+		}); err != nil {
 			return err
 		}
 		defer func() {
 			_, _ = a.target.Run(a.ctx, "rm -rf "+filepath.Dir(zipfile))
 		}()
 
-		if err := a.__deployInstallBinaryOnTarget(zipfile, machine, release); err != nil {
+		if err := a.tracker.Step("deploy.install-binary-on-target", func() error {
+			return a.__deployInstallBinaryOnTarget(zipfile, machine, release)
+		}); err != nil {
 			return err
 		}
 	} else {
 		tmpdir, err := os.MkdirTemp(os.TempDir(), "*-finch")
 		if err != nil {
-			return &DeployAgentError{Message: err.Error(), Reason: ""}
+			return &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 		}
 		defer func() {
 			_ = os.RemoveAll(tmpdir)
 		}()
 
-		zip, err := a.__deployDownloadRelease(release, alloyVersion, tmpdir)
-		if err != nil {
+		var zip string
+		if err := a.tracker.Step("deploy.download-release", func() (err error) {
+			zip, err = a.__deployDownloadRelease(release, alloyVersion, tmpdir)
+			return err
+		}); err != nil {
 			return err
 		}
 
-		binary, err := a.__deployUnzipRelease(release, zip)
-		if err != nil {
+		var binary string
+		if err := a.tracker.Step("deploy.unzip-release", func() (err error) {
+			binary, err = a.__deployUnzipRelease(release, zip)
+			return err
+		}); err != nil {
 			return err
 		}
 
-		if err := a.__deployInstallBinary(binary, machine); err != nil {
+		if err := a.tracker.Step("deploy.install-binary", func() error {
+			return a.__deployInstallBinary(binary, machine)
+		}); err != nil {
 			return err
 		}
 	}
 
 	switch machine.Kernel {
 	case "linux":
-		if err := a.__deployCopySystemdServiceUnit(); err != nil {
+		if err := a.tracker.Step("deploy.copy-systemd-service-unit", a.__deployCopySystemdServiceUnit); err != nil {
 			return err
 		}
-		if err := a.__deployEnableSystemdService(); err != nil {
+		if err := a.tracker.Step("deploy.enable-systemd-service", a.__deployEnableSystemdService); err != nil {
 			return err
 		}
 	case "freebsd":
-		if err := a.__deployCopyRcServiceFile(); err != nil {
+		if err := a.tracker.Step("deploy.copy-rc-service-file", a.__deployCopyRcServiceFile); err != nil {
 			return err
 		}
-		if err := a.__deployEnableRcService(); err != nil {
+		if err := a.tracker.Step("deploy.enable-rc-service", a.__deployEnableRcService); err != nil {
 			return err
 		}
 	case "darwin":
-		if err := a.__deployCopyLaunchdServiceFile(); err != nil {
+		if err := a.tracker.Step("deploy.copy-launchd-service-file", a.__deployCopyLaunchdServiceFile); err != nil {
 			return err
 		}
-		if err := a.__deployEnableLaunchdService(); err != nil {
+		if err := a.tracker.Step("deploy.enable-launchd-service", a.__deployEnableLaunchdService); err != nil {
 			return err
 		}
 	default:
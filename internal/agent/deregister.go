@@ -18,7 +18,7 @@ func (a *Agent) deregisterAgent(service, resourceID string) error {
 
 	ctx, client, err := grpc.NewClient(ctx, service, api.NewAgentServiceClient)
 	if err != nil {
-		return &DeregisterAgentError{Message: err.Error(), Reason: ""}
+		return &DeregisterAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = client.Close()
@@ -28,7 +28,7 @@ func (a *Agent) deregisterAgent(service, resourceID string) error {
 		Rid: resourceID,
 	})
 	if err != nil {
-		return &DeregisterAgentError{Message: err.Error(), Reason: ""}
+		return &DeregisterAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	return nil
@@ -53,7 +53,7 @@ func (a *Agent) describeAgent(service, rid string) (*DescribeData, error) {
 
 	ctx, client, err := grpc.NewClient(ctx, service, api.NewAgentServiceClient)
 	if err != nil {
-		return nil, &DescribeAgentError{Message: err.Error(), Reason: ""}
+		return nil, &DescribeAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = client.Close()
@@ -63,7 +63,7 @@ func (a *Agent) describeAgent(service, rid string) (*DescribeData, error) {
 		Rid: rid,
 	})
 	if err != nil {
-		return nil, &DescribeAgentError{Message: err.Error(), Reason: ""}
+		return nil, &DescribeAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	journal := false
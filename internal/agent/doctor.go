@@ -5,6 +5,8 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package agent
 
 import (
+	"fmt"
+
 	"github.com/fatih/color"
 )
 
@@ -39,7 +41,9 @@ func (a *Agent) __examineRequirements() (*[]Health, bool) {
 		list = append(list, Health{r, t, false, o})
 	}
 
-	verify(a.__requirementsHasSudo, "sudo", "available", "not available")
+	if tool := a.escalation.Tool(); tool != "" {
+		verify(a.__requirementsHasSudo, tool, "available", "not available")
+	}
 	verify(a.__requirementsHasSudoPermission, "superuser permission", "sufficient", "insufficient")
 
 	return &list, ok
@@ -74,19 +78,18 @@ func (a *Agent) __examinePorts(machine *MachineInfo) (*[]Health, bool) {
 
 	var cmd string
 	var exec string
-	var port string
 	var status string
 
 	switch machine.Kernel {
 	case "linux":
 		cmd = "ss"
-		exec = "sudo ss -H -tlpn sport = :" + port + " | grep -q ''"
+		exec = "sudo ss -H -tlpn sport = :%s | grep -q ''"
 	case "freebsd":
 		cmd = "sockstat"
-		exec = "sudo sockstat -q -P tcp -p " + port + " -l | grep -q ''"
+		exec = "sudo sockstat -q -P tcp -p %s -l | grep -q ''"
 	case "darwin":
 		cmd = "lsof"
-		exec = "sudo lsof -i :" + port + " | grep -q LISTEN"
+		exec = "sudo lsof -i :%s | grep -q LISTEN"
 	default:
 		// pass
 	}
@@ -106,7 +109,7 @@ func (a *Agent) __examinePorts(machine *MachineInfo) (*[]Health, bool) {
 
 	for port, optional := range ports {
 		o := true
-		_, err := a.target.Run(a.ctx, exec)
+		_, err := a.target.Run(a.ctx, fmt.Sprintf(exec, port))
 		if err == nil {
 			o = false
 			status = color.RedString("bound")
@@ -13,93 +13,157 @@ import (
 type DeployAgentError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *DeployAgentError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to deploy agent: %s %s", e.Message, e.Reason))
 }
 
+func (e *DeployAgentError) Unwrap() error {
+	return e.Err
+}
+
 type RegisterAgentError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *RegisterAgentError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to register agent: %s %s", e.Message, e.Reason))
 }
 
+func (e *RegisterAgentError) Unwrap() error {
+	return e.Err
+}
+
 type TeardownAgentError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *TeardownAgentError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to teardown agent: %s %s", e.Message, e.Reason))
 }
 
+func (e *TeardownAgentError) Unwrap() error {
+	return e.Err
+}
+
 type ListAgentsError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *ListAgentsError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to list agents: %s %s", e.Message, e.Reason))
 }
 
+func (e *ListAgentsError) Unwrap() error {
+	return e.Err
+}
+
 type DeregisterAgentError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *DeregisterAgentError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to deregister agent: %s %s", e.Message, e.Reason))
 }
 
+func (e *DeregisterAgentError) Unwrap() error {
+	return e.Err
+}
+
 type UpdateAgentError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *UpdateAgentError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to update agent: %s %s", e.Message, e.Reason))
 }
 
+func (e *UpdateAgentError) Unwrap() error {
+	return e.Err
+}
+
 type ConfigAgentError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *ConfigAgentError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to get agent config: %s %s", e.Message, e.Reason))
 }
 
+func (e *ConfigAgentError) Unwrap() error {
+	return e.Err
+}
+
 type DescribeAgentError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *DescribeAgentError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to get agent description: %s %s", e.Message, e.Reason))
 }
 
+func (e *DescribeAgentError) Unwrap() error {
+	return e.Err
+}
+
 type EditAgentError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *EditAgentError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to edit agent: %s %s", e.Message, e.Reason))
 }
 
+func (e *EditAgentError) Unwrap() error {
+	return e.Err
+}
+
 type DoctorAgentError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *DoctorAgentError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Target not healthy: %s %s", e.Message, e.Reason))
 }
 
+func (e *DoctorAgentError) Unwrap() error {
+	return e.Err
+}
+
+type ReadyAgentError struct {
+	Message string
+	Reason  string
+	Err     error
+}
+
+func (e *ReadyAgentError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("Agent not ready: %s %s", e.Message, e.Reason))
+}
+
+func (e *ReadyAgentError) Unwrap() error {
+	return e.Err
+}
+
 func convertError(err error, to any) error {
 	if err == nil {
 		return nil
@@ -129,6 +193,12 @@ func convertError(err error, to any) error {
 		toField.SetString(f.String())
 	}
 
+	f := elem.FieldByName("Err")
+	toField := reflect.ValueOf(to).Elem().FieldByName("Err")
+	if f.IsValid() && toField.IsValid() && toField.CanSet() && f.Type() == toField.Type() {
+		toField.Set(f)
+	}
+
 	if e, ok := to.(error); ok {
 		return e
 	}
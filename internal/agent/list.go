@@ -23,7 +23,7 @@ func (a *Agent) listAgents(service string) (*[]ListData, error) {
 
 	ctx, client, err := grpc.NewClient(ctx, service, api.NewAgentServiceClient)
 	if err != nil {
-		return nil, &ListAgentsError{Message: err.Error(), Reason: ""}
+		return nil, &ListAgentsError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = client.Close()
@@ -31,7 +31,7 @@ func (a *Agent) listAgents(service string) (*[]ListData, error) {
 
 	list, err := client.Handler().ListAgents(ctx, &api.ListAgentsRequest{})
 	if err != nil {
-		return nil, &ListAgentsError{Message: err.Error(), Reason: ""}
+		return nil, &ListAgentsError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	result := make([]ListData, 0, len(list.Agents))
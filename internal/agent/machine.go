@@ -46,12 +46,16 @@ func (a *Agent) __machineGetFreebsdArch(machine string) (string, error) {
 }
 
 func (a *Agent) machineInfo() (*MachineInfo, error) {
-	out, err := a.target.Run(a.ctx, "uname -sm")
+	run := a.target.Run
+	if a.planned {
+		run = a.target.RunForce
+	}
+	out, err := run(a.ctx, "uname -sm")
 	if err != nil {
-		return nil, &DeployAgentError{Message: err.Error(), Reason: ""}
+		return nil, &DeployAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
-	if a.dryRun {
+	if a.dryRun && !a.planned {
 		return &MachineInfo{
 			Kernel: "kernel",
 			Arch:   "arch",
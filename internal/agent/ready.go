@@ -0,0 +1,44 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+var (
+	readyMaxWait  = 60 * time.Second
+	readyInterval = 2 * time.Second
+)
+
+func (a *Agent) readyAgent() error {
+	if a.dryRun {
+		target.FprintProgress(a.out, "Skipping Alloy readiness check due to dry-run mode", a.format)
+		return nil
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%s/-/ready", ALLOY_HTTP_PORT)
+	cmd := fmt.Sprintf("curl -sf -o /dev/null %s || wget -q -O /dev/null %s", url, url)
+
+	deadline := time.Now().Add(readyMaxWait)
+	for {
+		out, err := a.target.Run(a.ctx, cmd)
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return &ReadyAgentError{
+				Message: fmt.Sprintf("Alloy not ready on port %s after %s", ALLOY_HTTP_PORT, readyMaxWait),
+				Reason:  string(out),
+			}
+		}
+
+		time.Sleep(readyInterval)
+	}
+}
@@ -28,7 +28,7 @@ func (a *Agent) registerAgent(service string, data *RegisterData) ([]byte, error
 
 	ctx, client, err := grpc.NewClient(ctx, service, api.NewAgentServiceClient)
 	if err != nil {
-		return nil, &RegisterAgentError{Message: err.Error(), Reason: ""}
+		return nil, &RegisterAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = client.Close()
@@ -44,14 +44,14 @@ func (a *Agent) registerAgent(service string, data *RegisterData) ([]byte, error
 		Node:           data.Node,
 	})
 	if err != nil {
-		return nil, &RegisterAgentError{Message: err.Error(), Reason: ""}
+		return nil, &RegisterAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	cfg, err := client.Handler().GetAgentConfig(ctx, &api.GetAgentConfigRequest{
 		Rid: register.Rid,
 	})
 	if err != nil {
-		return nil, &ConfigAgentError{Message: err.Error(), Reason: ""}
+		return nil, &ConfigAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	return cfg.Config, nil
@@ -5,25 +5,33 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package agent
 
 func (a *Agent) __requirementsHasSudo() error {
-	if _, err := a.target.Run(a.ctx, "command -v sudo"); err != nil {
-		return &DeployAgentError{Message: "sudo is not installed", Reason: err.Error()}
+	tool := a.escalation.Tool()
+	if tool == "" {
+		return nil
+	}
+	if _, err := a.target.Run(a.ctx, "command -v "+tool); err != nil {
+		return &DeployAgentError{Message: tool + " is not installed", Reason: err.Error()}
 	}
 	return nil
 }
 
 func (a *Agent) __requirementsHasSudoPermission() error {
-	if _, err := a.target.Run(a.ctx, "sudo -n true"); err != nil {
-		return &DeployAgentError{Message: "user has no sudo permission", Reason: err.Error()}
+	if _, err := a.target.Run(a.ctx, a.escalation.PermissionCheck()); err != nil {
+		tool := a.escalation.Tool()
+		if tool == "" {
+			return &DeployAgentError{Message: "user is not root", Reason: err.Error()}
+		}
+		return &DeployAgentError{Message: "user has no " + tool + " permission", Reason: err.Error()}
 	}
 	return nil
 }
 
 func (a *Agent) requirementsAgent() error {
-	if err := a.__requirementsHasSudo(); err != nil {
+	if err := a.tracker.Step("requirements.has-sudo", a.__requirementsHasSudo); err != nil {
 		return err
 	}
 
-	if err := a.__requirementsHasSudoPermission(); err != nil {
+	if err := a.tracker.Step("requirements.has-sudo-permission", a.__requirementsHasSudoPermission); err != nil {
 		return err
 	}
 
@@ -7,17 +7,17 @@ package agent
 func (a *Agent) __teardownSystemdService() error {
 	out, err := a.target.Run(a.ctx, "sudo systemctl stop alloy.service || true")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = a.target.Run(a.ctx, "sudo systemctl disable alloy.service || true")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = a.target.Run(a.ctx, "sudo rm -f /etc/systemd/system/alloy.service")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -26,17 +26,17 @@ func (a *Agent) __teardownSystemdService() error {
 func (a *Agent) __teardownRcService() error {
 	out, err := a.target.Run(a.ctx, "sudo service alloy stop || true")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = a.target.Run(a.ctx, "sudo sysrc -x alloy_enable || true")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = a.target.Run(a.ctx, "sudo rm -f /etc/rc.d/alloy")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -45,12 +45,12 @@ func (a *Agent) __teardownRcService() error {
 func (a *Agent) __teardownLaunchdService() error {
 	out, err := a.target.Run(a.ctx, "sudo launchctl bootout system/com.github.tschaefer.finch.agent || true")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = a.target.Run(a.ctx, "sudo rm -f /Library/LaunchDaemons/com.github.tschaefer.finch.agent.plist")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -74,12 +74,12 @@ func (a *Agent) teardownAgent(machine *MachineInfo) error {
 
 	out, err := a.target.Run(a.ctx, "sudo rm -rf /etc/alloy")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = a.target.Run(a.ctx, "sudo rm -rf /var/lib/alloy")
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	path := "/usr/bin/alloy"
@@ -89,7 +89,7 @@ func (a *Agent) teardownAgent(machine *MachineInfo) error {
 
 	out, err = a.target.Run(a.ctx, "sudo rm -f "+path)
 	if err != nil {
-		return &TeardownAgentError{Message: err.Error(), Reason: string(out)}
+		return &TeardownAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
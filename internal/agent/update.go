@@ -29,12 +29,12 @@ func (a *Agent) __updateServiceBinaryGetLatestTag() (string, error) {
 
 	req, err := http.NewRequestWithContext(reqCtx, "GET", url, nil)
 	if err != nil {
-		return "", &UpdateAgentError{Message: err.Error(), Reason: ""}
+		return "", &UpdateAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", &UpdateAgentError{Message: err.Error(), Reason: ""}
+		return "", &UpdateAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -46,13 +46,13 @@ func (a *Agent) __updateServiceBinaryGetLatestTag() (string, error) {
 
 	out, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", &UpdateAgentError{Message: err.Error(), Reason: ""}
+		return "", &UpdateAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	a.__helperPrintProgress("Running 'JSON unmarshal \"tag_name\"'")
 	var data any
 	if err := json.Unmarshal(out, &data); err != nil {
-		return "", &UpdateAgentError{Message: err.Error(), Reason: string(out)}
+		return "", &UpdateAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return data.(map[string]any)["tag_name"].(string), nil
@@ -60,7 +60,8 @@ func (a *Agent) __updateServiceBinaryGetLatestTag() (string, error) {
 
 func (a *Agent) __updateServiceBinaryIsNeeded(version string, machine *MachineInfo) (bool, error) {
 	if a.dryRun {
-		target.PrintProgress(
+		target.FprintProgress(
+			a.out,
 			fmt.Sprintf("Skipping Alloy update check for version '%s' due to dry-run mode", version),
 			a.format,
 		)
@@ -83,7 +84,7 @@ func (a *Agent) __updateServiceBinaryIsNeeded(version string, machine *MachineIn
 
 	out, err := a.target.Run(a.ctx, path+" --version | grep -o -E 'v[0-9\\.]+'")
 	if err != nil {
-		return false, &UpdateAgentError{Message: err.Error(), Reason: string(out)}
+		return false, &UpdateAgentError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 	currentVersion := strings.TrimSpace(string(out))
 
@@ -102,7 +103,7 @@ func (a *Agent) __updateServiceBinary(machine *MachineInfo, version string) erro
 
 	tmpdir, err := os.MkdirTemp(os.TempDir(), "*-finch")
 	if err != nil {
-		return &UpdateAgentError{Message: err.Error(), Reason: ""}
+		return &UpdateAgentError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = os.RemoveAll(tmpdir)
@@ -154,38 +155,48 @@ func (a *Agent) __updateServiceBinaryOnTarget(machine *MachineInfo, version stri
 
 func (a *Agent) updateAgent(machine *MachineInfo, skipConfig bool, skipBinaries bool, version string) error {
 	if !skipConfig {
-		if err := a.__deployCopyConfigFile(); err != nil {
+		if err := a.tracker.Step("update.copy-config-file", a.__deployCopyConfigFile); err != nil {
 			return convertError(err, &UpdateAgentError{})
 		}
 	}
 
 	if !skipBinaries {
 		if a.additionsAgent() {
-			if err := a.__updateServiceBinaryOnTarget(machine, version); err != nil {
+			if err := a.tracker.Step("update.service-binary-on-target", func() error {
+				return a.__updateServiceBinaryOnTarget(machine, version)
+			}); err != nil {
 				return convertError(err, &UpdateAgentError{})
 			}
 		} else {
-			if err := a.__updateServiceBinary(machine, version); err != nil {
+			if err := a.tracker.Step("update.service-binary", func() error {
+				return a.__updateServiceBinary(machine, version)
+			}); err != nil {
 				return convertError(err, &UpdateAgentError{})
 			}
 		}
 	}
 
+	return a.tracker.Step("update.restart-service", func() error {
+		return a.__updateRestartService(machine)
+	})
+}
+
+func (a *Agent) __updateRestartService(machine *MachineInfo) error {
 	switch machine.Kernel {
 	case "linux":
 		out, err := a.target.Run(a.ctx, "sudo systemctl restart alloy.service")
 		if err != nil {
-			return &UpdateAgentError{Message: err.Error(), Reason: string(out)}
+			return &UpdateAgentError{Message: err.Error(), Reason: string(out), Err: err}
 		}
 	case "freebsd":
 		out, err := a.target.Run(a.ctx, "sudo service alloy restart")
 		if err != nil {
-			return &UpdateAgentError{Message: err.Error(), Reason: string(out)}
+			return &UpdateAgentError{Message: err.Error(), Reason: string(out), Err: err}
 		}
 	case "darwin":
 		out, err := a.target.Run(a.ctx, "sudo launchctl kickstart -k system/com.github.tschaefer.finch.agent")
 		if err != nil {
-			return &UpdateAgentError{Message: err.Error(), Reason: string(out)}
+			return &UpdateAgentError{Message: err.Error(), Reason: string(out), Err: err}
 		}
 	default:
 		// no-op
@@ -122,6 +122,53 @@ func RemoveStack(name string) error {
 	return write(stacks)
 }
 
+// ExportStack writes the stack with its client certificate and key to file,
+// to be added to the config with ImportStack.
+func ExportStack(file, name string, certPEM, keyPEM []byte) error {
+	data, err := json.MarshalIndent(Stack{
+		Name: name,
+		Cert: base64.StdEncoding.EncodeToString(certPEM),
+		Key:  base64.StdEncoding.EncodeToString(keyPEM),
+	}, "", "  ")
+	if err != nil {
+		return &ConfigError{Message: "failed to encode stack", Reason: err.Error()}
+	}
+
+	if err := os.WriteFile(file, append(data, '\n'), 0600); err != nil {
+		return &ConfigError{Message: "failed to write stack", Reason: err.Error()}
+	}
+
+	return nil
+}
+
+// ImportStack adds the stack of a file written by ExportStack to the config
+// and returns its name.
+func ImportStack(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", &ConfigError{Message: "failed to read stack", Reason: err.Error()}
+	}
+
+	var stack Stack
+	if err := json.Unmarshal(data, &stack); err != nil {
+		return "", &ConfigError{Message: "failed to parse stack", Reason: err.Error()}
+	}
+	if stack.Name == "" || stack.Cert == "" || stack.Key == "" {
+		return "", &ConfigError{Message: "incomplete stack", Reason: file}
+	}
+
+	certPEM, err := base64.StdEncoding.DecodeString(stack.Cert)
+	if err != nil {
+		return "", &ConfigError{Message: "failed to decode certificate", Reason: err.Error()}
+	}
+	keyPEM, err := base64.StdEncoding.DecodeString(stack.Key)
+	if err != nil {
+		return "", &ConfigError{Message: "failed to decode key", Reason: err.Error()}
+	}
+
+	return stack.Name, UpdateStack(stack.Name, certPEM, keyPEM)
+}
+
 func ListStacks() ([]string, error) {
 	if !exist() {
 		return nil, &ConfigError{Message: "config file does not exist", Reason: ""}
@@ -140,6 +140,37 @@ func Test_LookupStackReturnErrorIfStackNotExist2(t *testing.T) {
 	assert.EqualError(t, err, wanted, "lookup stack certs")
 }
 
+func Test_ExportAndImportStack(t *testing.T) {
+	cfgLoc := setup(t)
+	defer teardown(cfgLoc, t)
+
+	stack := newStack()
+	file := t.TempDir() + "/credentials.json"
+	err := ExportStack(file, stack.Hostname, stack.Cert, stack.Key)
+	assert.NoError(t, err, "export stack")
+
+	info, err := os.Stat(file)
+	assert.NoError(t, err, "stat exported stack")
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "exported stack mode")
+
+	_, err = LookupStack(stack.Hostname)
+	assert.Error(t, err, "export does not touch the config")
+
+	name, err := ImportStack(file)
+	assert.NoError(t, err, "import stack")
+	assert.Equal(t, stack.Hostname, name, "stack name")
+
+	imported, err := LookupStack(stack.Hostname)
+	assert.NoError(t, err, "lookup imported stack")
+	assert.Equal(t, string(stack.Cert), imported.Cert, "certificate")
+	assert.Equal(t, string(stack.Key), imported.Key, "key")
+
+	err = os.WriteFile(file, []byte(`{ "name": "finch.example.com" }`), 0600)
+	assert.NoError(t, err, "write incomplete stack")
+	_, err = ImportStack(file)
+	assert.ErrorContains(t, err, "incomplete stack", "import incomplete stack")
+}
+
 func setup(t *testing.T) string {
 	cfgLoc, err := os.MkdirTemp("", "finch-test")
 	assert.NoError(t, err, "create temp dir for config")
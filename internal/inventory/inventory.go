@@ -0,0 +1,184 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package inventory
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+type SSH struct {
+	Identity      string `yaml:"identity"`
+	Jump          string `yaml:"jump"`
+	HostKeyPolicy string `yaml:"host_key_policy"`
+}
+
+// Vars are the settings of a host, the defaults of the inventory apply to
+// every host not setting them itself.
+type Vars struct {
+	AgentConfig  string            `yaml:"agent_config"`
+	AlloyVersion string            `yaml:"alloy_version"`
+	SSH          SSH               `yaml:"ssh"`
+	Labels       map[string]string `yaml:"labels"`
+}
+
+type Host struct {
+	URL    string   `yaml:"host"`
+	Groups []string `yaml:"groups"`
+	Vars   `yaml:",inline"`
+}
+
+type Inventory struct {
+	Defaults Vars   `yaml:"defaults"`
+	Hosts    []Host `yaml:"hosts"`
+}
+
+// UnmarshalYAML accepts a host given as plain [user@]host[:port] as well.
+func (h *Host) UnmarshalYAML(unmarshal func(any) error) error {
+	var url string
+	if err := unmarshal(&url); err == nil {
+		*h = Host{URL: url}
+		return nil
+	}
+
+	type plain Host
+	return unmarshal((*plain)(h))
+}
+
+// Load reads an inventory file and applies its defaults to the hosts.
+// Relative paths of agent configurations and identities are resolved against
+// the directory of the inventory file, a leading ~/ against the home
+// directory.
+func Load(file string) (*Inventory, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	var inv Inventory
+	if err := yaml.UnmarshalWithOptions(data, &inv, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("invalid inventory %s: %w", file, err)
+	}
+
+	if len(inv.Hosts) == 0 {
+		return nil, fmt.Errorf("invalid inventory %s: no hosts", file)
+	}
+
+	dir := filepath.Dir(file)
+	for i := range inv.Hosts {
+		host := &inv.Hosts[i]
+		if host.URL == "" {
+			return nil, fmt.Errorf("invalid inventory %s: host %d has no address", file, i+1)
+		}
+		if slices.ContainsFunc(inv.Hosts[:i], func(h Host) bool { return h.URL == host.URL }) {
+			return nil, fmt.Errorf("invalid inventory %s: duplicate host %s", file, host.URL)
+		}
+
+		host.Vars = merge(host.Vars, inv.Defaults)
+		host.AgentConfig = resolve(dir, host.AgentConfig)
+		host.SSH.Identity = resolve(dir, host.SSH.Identity)
+	}
+
+	return &inv, nil
+}
+
+// Select returns the hosts matching one of the limits, all hosts if there
+// are none. A limit is either a group or a label given as key=value.
+func (inv *Inventory) Select(limits []string) ([]Host, error) {
+	if len(limits) == 0 {
+		return inv.Hosts, nil
+	}
+
+	for _, limit := range limits {
+		if strings.Contains(limit, "=") {
+			continue
+		}
+		if !slices.Contains(inv.Groups(), limit) {
+			return nil, fmt.Errorf("unknown inventory group %s", limit)
+		}
+	}
+
+	var hosts []Host
+	for _, host := range inv.Hosts {
+		if slices.ContainsFunc(limits, host.matches) {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no inventory host matches %s", strings.Join(limits, ","))
+	}
+
+	return hosts, nil
+}
+
+// Groups returns the sorted names of all groups.
+func (inv *Inventory) Groups() []string {
+	var groups []string
+	for _, host := range inv.Hosts {
+		for _, group := range host.Groups {
+			if !slices.Contains(groups, group) {
+				groups = append(groups, group)
+			}
+		}
+	}
+	slices.Sort(groups)
+
+	return groups
+}
+
+func (h Host) matches(limit string) bool {
+	if key, value, ok := strings.Cut(limit, "="); ok {
+		v, found := h.Labels[key]
+		return found && v == value
+	}
+
+	return slices.Contains(h.Groups, limit)
+}
+
+func merge(vars, defaults Vars) Vars {
+	if vars.AgentConfig == "" {
+		vars.AgentConfig = defaults.AgentConfig
+	}
+	if vars.AlloyVersion == "" {
+		vars.AlloyVersion = defaults.AlloyVersion
+	}
+	if vars.SSH.Identity == "" {
+		vars.SSH.Identity = defaults.SSH.Identity
+	}
+	if vars.SSH.Jump == "" {
+		vars.SSH.Jump = defaults.SSH.Jump
+	}
+	if vars.SSH.HostKeyPolicy == "" {
+		vars.SSH.HostKeyPolicy = defaults.SSH.HostKeyPolicy
+	}
+
+	labels := maps.Clone(defaults.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	maps.Copy(labels, vars.Labels)
+	vars.Labels = labels
+
+	return vars
+}
+
+func resolve(dir, file string) string {
+	if rest, ok := strings.CutPrefix(file, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if file == "" || filepath.IsAbs(file) || strings.HasPrefix(file, "~") {
+		return file
+	}
+
+	return filepath.Join(dir, file)
+}
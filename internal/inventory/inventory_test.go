@@ -0,0 +1,122 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func write(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "hosts.yaml")
+	err := os.WriteFile(file, []byte(content), 0600)
+	assert.NoError(t, err, "write inventory")
+
+	return file
+}
+
+const inventory = `
+defaults:
+  agent_config: agents/default.cfg
+  alloy_version: v1.8.0
+  ssh:
+    identity: ~/.ssh/id_ed25519
+    host_key_policy: strict
+  labels:
+    env: production
+hosts:
+  - deploy@web1.example.com
+  - host: web2.example.com:2222
+    groups: [web, eu]
+    agent_config: /etc/finch/web2.cfg
+    ssh:
+      jump: bastion.example.com
+    labels:
+      role: frontend
+  - host: docker://db1
+    groups: [db]
+    alloy_version: latest
+    labels:
+      env: staging
+`
+
+func Test_LoadAppliesDefaultsToHosts(t *testing.T) {
+	t.Setenv("HOME", "/home/finch")
+	file := write(t, inventory)
+	dir := filepath.Dir(file)
+
+	inv, err := Load(file)
+	assert.NoError(t, err, "load inventory")
+	assert.Len(t, inv.Hosts, 3, "hosts")
+
+	web1 := inv.Hosts[0]
+	assert.Equal(t, "deploy@web1.example.com", web1.URL, "plain host")
+	assert.Equal(t, filepath.Join(dir, "agents/default.cfg"), web1.AgentConfig, "relative config resolved")
+	assert.Equal(t, "v1.8.0", web1.AlloyVersion, "default version")
+	assert.Equal(t, SSH{Identity: "/home/finch/.ssh/id_ed25519", HostKeyPolicy: "strict"}, web1.SSH, "default ssh")
+
+	web2 := inv.Hosts[1]
+	assert.Equal(t, "/etc/finch/web2.cfg", web2.AgentConfig, "host config")
+	assert.Equal(t, SSH{Identity: "/home/finch/.ssh/id_ed25519", Jump: "bastion.example.com", HostKeyPolicy: "strict"}, web2.SSH, "merged ssh")
+	assert.Equal(t, map[string]string{"env": "production", "role": "frontend"}, web2.Labels, "merged labels")
+
+	db1 := inv.Hosts[2]
+	assert.Equal(t, "latest", db1.AlloyVersion, "host version")
+	assert.Equal(t, map[string]string{"env": "staging"}, db1.Labels, "overridden label")
+
+	assert.Equal(t, []string{"db", "eu", "web"}, inv.Groups(), "groups")
+}
+
+func Test_SelectReturnsHostsMatchingLimit(t *testing.T) {
+	inv, err := Load(write(t, inventory))
+	assert.NoError(t, err, "load inventory")
+
+	urls := func(hosts []Host) []string {
+		var urls []string
+		for _, h := range hosts {
+			urls = append(urls, h.URL)
+		}
+		return urls
+	}
+
+	hosts, err := inv.Select(nil)
+	assert.NoError(t, err)
+	assert.Len(t, hosts, 3, "no limit")
+
+	hosts, err = inv.Select([]string{"web", "db"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web2.example.com:2222", "docker://db1"}, urls(hosts), "groups")
+
+	hosts, err = inv.Select([]string{"env=production"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"deploy@web1.example.com", "web2.example.com:2222"}, urls(hosts), "label")
+
+	_, err = inv.Select([]string{"mail"})
+	assert.EqualError(t, err, "unknown inventory group mail", "unknown group")
+
+	_, err = inv.Select([]string{"env=testing"})
+	assert.EqualError(t, err, "no inventory host matches env=testing", "no match")
+}
+
+func Test_LoadReturnsErrorIfInventoryIsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"hosts:\n  - web1\n  - web1\n":           "duplicate host web1",
+		"hosts: []\n":                            "no hosts",
+		"servers:\n  - web1\n":                   "unknown field \"servers\"",
+		"hosts:\n  - groups: [web]\n":            "host 1 has no address",
+		"hosts:\n  - host: web1\n    agent: x\n": "unknown field \"agent\"",
+	}
+
+	for content, wanted := range tests {
+		_, err := Load(write(t, content))
+		assert.ErrorContains(t, err, wanted, "invalid inventory")
+	}
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read inventory", "missing inventory")
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package inventory
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+type RolloutOptions struct {
+	BatchSize   uint
+	MaxFailures uint
+	Pause       time.Duration
+}
+
+// Rollout calls f for the hosts in batches, the hosts of a batch in
+// parallel. Once more than MaxFailures hosts failed, the remaining hosts are
+// skipped and Rollout reports the rollout as stopped.
+func Rollout(w io.Writer, hosts []Host, opts RolloutOptions, format target.Format, f func(host Host, out io.Writer) error) ([]Result, bool) {
+	size := int(max(opts.BatchSize, 1))
+	batches := (len(hosts) + size - 1) / size
+
+	var results []Result
+	failed := 0
+	for i := 0; i < len(hosts); i += size {
+		batch := hosts[i:min(i+size, len(hosts))]
+
+		var urls []string
+		for _, host := range batch {
+			urls = append(urls, host.URL)
+		}
+		report(w, fmt.Sprintf("Rolling out batch %d of %d: %s", i/size+1, batches, strings.Join(urls, ", ")), format)
+
+		batchResults := Run(w, batch, uint(size), format, f)
+		results = append(results, batchResults...)
+
+		failed += Failed(batchResults)
+		if failed > int(opts.MaxFailures) {
+			for _, host := range hosts[i+len(batch):] {
+				results = append(results, Result{Host: host.URL, Skipped: true})
+			}
+			report(w, fmt.Sprintf("Stopping rollout after %d failed hosts, maximum is %d", failed, opts.MaxFailures), format)
+			return results, true
+		}
+
+		if opts.Pause > 0 && i+size < len(hosts) {
+			report(w, fmt.Sprintf("Pausing %s before next batch", opts.Pause), format)
+			time.Sleep(opts.Pause)
+		}
+	}
+
+	return results, false
+}
+
+// Skipped returns the number of hosts skipped by a stopped rollout.
+func Skipped(results []Result) int {
+	skipped := 0
+	for _, r := range results {
+		if r.Skipped {
+			skipped++
+		}
+	}
+
+	return skipped
+}
+
+// report prints the course of the rollout, except in progress format where
+// it would be just dots between the ones of the hosts.
+func report(w io.Writer, message string, format target.Format) {
+	if format == target.FormatProgress {
+		return
+	}
+
+	target.FprintProgress(w, message, format)
+}
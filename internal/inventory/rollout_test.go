@@ -0,0 +1,70 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package inventory
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func hosts(urls ...string) []Host {
+	var hosts []Host
+	for _, url := range urls {
+		hosts = append(hosts, Host{URL: url})
+	}
+
+	return hosts
+}
+
+func Test_RolloutUpdatesAllBatches(t *testing.T) {
+	var buf bytes.Buffer
+	var mutex sync.Mutex
+	var updated []string
+
+	results, stopped := Rollout(&buf, hosts("web1", "web2", "web3"), RolloutOptions{BatchSize: 2}, target.FormatDocumentation, func(host Host, out io.Writer) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		updated = append(updated, host.URL)
+		return nil
+	})
+
+	assert.False(t, stopped, "rollout completed")
+	assert.ElementsMatch(t, []string{"web1", "web2", "web3"}, updated, "updated hosts")
+	assert.Len(t, results, 3, "results")
+	assert.Equal(t, 0, Failed(results), "failed hosts")
+	assert.Contains(t, buf.String(), "Rolling out batch 1 of 2: web1, web2\n", "first batch")
+	assert.Contains(t, buf.String(), "Rolling out batch 2 of 2: web3\n", "second batch")
+}
+
+func Test_RolloutStopsWhenFailuresExceedMaximum(t *testing.T) {
+	var buf bytes.Buffer
+	var mutex sync.Mutex
+	calls := 0
+
+	results, stopped := Rollout(&buf, hosts("web1", "web2", "web3", "web4", "web5"), RolloutOptions{BatchSize: 1, MaxFailures: 1}, target.FormatDocumentation, func(host Host, out io.Writer) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls++
+		if host.URL == "web2" || host.URL == "web3" {
+			return errors.New("Agent not ready: Alloy not ready on port 12345")
+		}
+		return nil
+	})
+
+	assert.True(t, stopped, "rollout stopped")
+	assert.Equal(t, 3, calls, "hosts run")
+	assert.Equal(t, []string{"success", "failure", "failure", "skipped", "skipped"}, []string{
+		status(results[0]), status(results[1]), status(results[2]), status(results[3]), status(results[4]),
+	}, "host states")
+	assert.Equal(t, 2, Failed(results), "failed hosts")
+	assert.Equal(t, 2, Skipped(results), "skipped hosts")
+	assert.Contains(t, buf.String(), "Stopping rollout after 2 failed hosts, maximum is 1\n", "stop report")
+}
@@ -0,0 +1,184 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+type Result struct {
+	Host     string
+	Err      error
+	Skipped  bool
+	Duration time.Duration
+}
+
+type sharedWriter struct {
+	w     io.Writer
+	mutex sync.Mutex
+}
+
+// prefixWriter passes whole lines to the shared writer, prefixed with the
+// host. In JSON format the host is added as field instead.
+type prefixWriter struct {
+	host   string
+	format target.Format
+	shared *sharedWriter
+	mutex  sync.Mutex
+	buf    bytes.Buffer
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.buf.Write(b)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := p.buf.Next(i + 1)
+		p.emit(string(line[:i]))
+	}
+
+	return len(b), nil
+}
+
+// Flush writes a pending incomplete line, like the dots of the progress
+// format.
+func (p *prefixWriter) Flush() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.buf.Len() > 0 {
+		p.emit(p.buf.String())
+		p.buf.Reset()
+	}
+}
+
+func (p *prefixWriter) emit(line string) {
+	if p.format == target.FormatJSON && strings.HasPrefix(line, "{") {
+		var data map[string]any
+		if err := json.Unmarshal([]byte(line), &data); err == nil {
+			data["host"] = p.host
+			if jsonData, err := json.Marshal(data); err == nil {
+				line = string(jsonData)
+			}
+		}
+	} else {
+		line = fmt.Sprintf("[%s] %s", p.host, line)
+	}
+
+	p.shared.mutex.Lock()
+	defer p.shared.mutex.Unlock()
+	_, _ = fmt.Fprintln(p.shared.w, line)
+}
+
+// Run calls f for every host, at most parallel at a time. The writer passed
+// to f prefixes each printed line with the host before writing it to w. The
+// results are in the order of hosts.
+func Run(w io.Writer, hosts []Host, parallel uint, format target.Format, f func(host Host, out io.Writer) error) []Result {
+	if parallel == 0 {
+		parallel = 1
+	}
+
+	shared := &sharedWriter{w: w}
+	results := make([]Result, len(hosts))
+	sem := make(chan struct{}, parallel)
+
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			out := &prefixWriter{host: host.URL, format: format, shared: shared}
+			start := time.Now()
+			err := f(host, out)
+			out.Flush()
+			results[i] = Result{Host: host.URL, Err: err, Duration: time.Since(start)}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Failed returns the number of hosts f failed for.
+func Failed(results []Result) int {
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// PrintResults prints a table with the outcome per host, or a JSON line per
+// host in JSON format.
+func PrintResults(w io.Writer, results []Result, format target.Format) {
+	if format == target.FormatJSON {
+		for _, r := range results {
+			data := map[string]string{
+				"timestamp": time.Now().Format(time.RFC3339),
+				"host":      r.Host,
+				"status":    status(r),
+			}
+			if !r.Skipped {
+				data["duration"] = r.Duration.Round(time.Millisecond).String()
+			}
+			if r.Err != nil {
+				data["error"] = r.Err.Error()
+			}
+			jsonData, err := json.Marshal(data)
+			if err != nil {
+				continue
+			}
+			_, _ = fmt.Fprintln(w, string(jsonData))
+		}
+		return
+	}
+
+	t := tablewriter.NewWriter(w)
+	t.Header([]string{"Host", "Status", "Duration", "Error"})
+	for _, r := range results {
+		duration, reason := "", ""
+		if !r.Skipped {
+			duration = r.Duration.Round(time.Second).String()
+		}
+		if r.Err != nil {
+			reason = r.Err.Error()
+		}
+		_ = t.Append([]string{r.Host, status(r), duration, reason})
+	}
+	_ = t.Render()
+}
+
+func status(r Result) string {
+	if r.Skipped {
+		return "skipped"
+	}
+	if r.Err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
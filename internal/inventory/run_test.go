@@ -0,0 +1,86 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package inventory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func Test_RunLimitsParallelHostsAndKeepsOrder(t *testing.T) {
+	var hosts []Host
+	for _, url := range []string{"web1", "web2", "web3", "web4", "web5"} {
+		hosts = append(hosts, Host{URL: url})
+	}
+
+	var mutex sync.Mutex
+	running, peak := 0, 0
+	var buf bytes.Buffer
+	results := Run(&buf, hosts, 2, target.FormatDocumentation, func(host Host, out io.Writer) error {
+		mutex.Lock()
+		running++
+		peak = max(peak, running)
+		mutex.Unlock()
+
+		_, _ = fmt.Fprintf(out, "Running 'uname' as root@%s\n", host.URL)
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+
+		if host.URL == "web3" {
+			return errors.New("Failed to deploy agent: unreachable")
+		}
+		return nil
+	})
+
+	assert.Equal(t, 2, peak, "hosts run at the same time")
+	assert.Len(t, results, 5, "results")
+	for i, r := range results {
+		assert.Equal(t, hosts[i].URL, r.Host, "result order")
+	}
+	assert.EqualError(t, results[2].Err, "Failed to deploy agent: unreachable", "failed host")
+	assert.Equal(t, 1, Failed(results), "failed hosts")
+	assert.Contains(t, buf.String(), "[web4] Running 'uname' as root@web4\n", "prefixed output")
+}
+
+func Test_RunAddsHostToJSONAndFlushesProgress(t *testing.T) {
+	var buf bytes.Buffer
+	Run(&buf, []Host{{URL: "web1"}}, 1, target.FormatJSON, func(host Host, out io.Writer) error {
+		_, _ = fmt.Fprintln(out, `{"message":"Running 'uname'"}`)
+		_, _ = fmt.Fprint(out, "...")
+		return nil
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, `{"host":"web1","message":"Running 'uname'"}`, lines[0], "host field")
+	assert.Equal(t, "[web1] ...", lines[1], "pending output flushed")
+}
+
+func Test_PrintResultsShowsReasonPerHost(t *testing.T) {
+	results := []Result{
+		{Host: "web1", Duration: 3 * time.Second},
+		{Host: "web2", Err: errors.New("Failed to deploy agent: curl is not installed")},
+	}
+
+	var buf bytes.Buffer
+	PrintResults(&buf, results, target.FormatProgress)
+	assert.Regexp(t, `web1\s+│\s+success`, buf.String(), "successful host")
+	assert.Regexp(t, `web2\s+│\s+failure\s+│\s+0s\s+│\s+Failed to deploy agent: curl is not installed`, buf.String(), "failed host")
+
+	buf.Reset()
+	PrintResults(&buf, results, target.FormatJSON)
+	assert.Contains(t, buf.String(), `"error":"Failed to deploy agent: curl is not installed","host":"web2","status":"failure"`, "JSON result")
+}
@@ -0,0 +1,341 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tschaefer/finchctl/internal/target"
+	"github.com/tschaefer/finchctl/internal/version"
+)
+
+const backupManifestFile = "manifest.json"
+
+// BackupComponents maps the components of a backup to their paths in the lib
+// directory and the services writing to them. The config component is always
+// part of a backup.
+var BackupComponents = map[string]struct {
+	Paths    []string
+	Services []string
+}{
+	"config": {
+		Paths: []string{
+			"docker-compose.yaml",
+			"stack.json",
+			"finch.json",
+			"finch.db",
+			"alloy/etc",
+			"grafana/alerting",
+			"grafana/dashboards",
+			"loki/etc",
+			"mimir/etc",
+			"pyroscope/etc",
+			"traefik/etc",
+		},
+		Services: []string{"finch", "grafana"},
+	},
+	"grafana":  {Paths: []string{"grafana"}, Services: []string{"grafana"}},
+	"logs":     {Paths: []string{"loki/data"}, Services: []string{"loki"}},
+	"metrics":  {Paths: []string{"mimir/data"}, Services: []string{"mimir"}},
+	"profiles": {Paths: []string{"pyroscope/data"}, Services: []string{"pyroscope"}},
+}
+
+type BackupManifest struct {
+	Version    int               `json:"version"`
+	CreatedAt  string            `json:"created_at"`
+	StackID    string            `json:"stack_id"`
+	Hostname   string            `json:"hostname"`
+	Release    string            `json:"release"`
+	Components []string          `json:"components"`
+	Images     []string          `json:"images"`
+	Checksums  map[string]string `json:"checksums"`
+}
+
+type BackupOptions struct {
+	Output     string
+	Components []string
+}
+
+// backupCompression returns the tar option compressing an archive named
+// file.
+func backupCompression(file string) (string, error) {
+	switch {
+	case strings.HasSuffix(file, ".tar.zst"), strings.HasSuffix(file, ".tzst"):
+		return "--zstd", nil
+	case strings.HasSuffix(file, ".tar.gz"), strings.HasSuffix(file, ".tgz"):
+		return "--gzip", nil
+	case strings.HasSuffix(file, ".tar"):
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported archive format of %s, use .tar.zst, .tar.gz or .tar", file)
+	}
+}
+
+func (s *Service) __backupComponents(components []string) ([]string, error) {
+	selected := []string{"config"}
+	for _, c := range components {
+		if c == "all" {
+			for name := range BackupComponents {
+				if !slices.Contains(selected, name) {
+					selected = append(selected, name)
+				}
+			}
+			continue
+		}
+		if _, ok := BackupComponents[c]; !ok {
+			return nil, &BackupServiceError{Message: "unknown backup component " + c, Reason: ""}
+		}
+		if !slices.Contains(selected, c) {
+			selected = append(selected, c)
+		}
+	}
+	slices.Sort(selected)
+
+	return selected, nil
+}
+
+// backupPaths returns the paths and the services of the components. A path
+// within the path of another selected component is left out, the archive
+// holds each file once.
+func backupPaths(components []string) ([]string, []string) {
+	var all, services []string
+	for _, c := range components {
+		all = append(all, BackupComponents[c].Paths...)
+		services = append(services, BackupComponents[c].Services...)
+	}
+
+	var paths []string
+	for _, p := range all {
+		covered := slices.ContainsFunc(all, func(parent string) bool {
+			return strings.HasPrefix(p, parent+"/")
+		})
+		if !covered && !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(services)
+
+	return paths, slices.Compact(services)
+}
+
+func (s *Service) __backupReadFinchConfig() (*FinchConfig, error) {
+	cfgPath := path.Join(s.libDir(), "finch.json")
+	out, err := s.target.RunForce(s.ctx, "sudo cat "+cfgPath)
+	if err != nil {
+		return nil, &BackupServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	var cfg FinchConfig
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		return nil, &BackupServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	return &cfg, nil
+}
+
+func (s *Service) __backupImages() ([]string, error) {
+	out, err := s.target.RunForce(s.ctx, "sudo docker compose --file "+path.Join(s.libDir(), "docker-compose.yaml")+" config --images")
+	if err != nil {
+		return nil, &BackupServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	images := strings.Fields(string(out))
+	slices.Sort(images)
+
+	return images, nil
+}
+
+// __backupChecksums returns the SHA-256 checksums of the files below the
+// paths, relative to the lib directory. Missing paths are skipped.
+func (s *Service) __backupChecksums(paths []string) (map[string]string, []string, error) {
+	var existing []string
+	for _, p := range paths {
+		if _, err := s.target.Run(s.ctx, "sudo test -e "+path.Join(s.libDir(), p)); err == nil {
+			existing = append(existing, p)
+		}
+	}
+
+	checksums := map[string]string{}
+	if len(existing) == 0 {
+		return checksums, existing, nil
+	}
+
+	var dirs []string
+	for _, p := range existing {
+		dirs = append(dirs, path.Join(s.libDir(), p))
+	}
+	out, err := s.target.Run(s.ctx, fmt.Sprintf("sudo find %s -type f -exec sha256sum {} +", strings.Join(dirs, " ")))
+	if err != nil {
+		return nil, nil, &BackupServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+		sum, file, ok := strings.Cut(line, "  ")
+		if ok {
+			checksums[strings.TrimPrefix(file, s.libDir()+"/")] = sum
+		}
+	}
+
+	return checksums, existing, nil
+}
+
+func (s *Service) __backupWriteManifest(dir string, manifest *BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return &BackupServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	f, err := os.CreateTemp("", "finch-manifest-*.json")
+	if err != nil {
+		return &BackupServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return &BackupServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+	if err := f.Close(); err != nil {
+		return &BackupServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	if out, err := s.target.Copy(s.ctx, f.Name(), path.Join(dir, backupManifestFile), "400", "0:0"); err != nil {
+		return &BackupServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	return nil
+}
+
+func (s *Service) backupService(opts BackupOptions) error {
+	compression, err := backupCompression(opts.Output)
+	if err != nil {
+		return &BackupServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+	if compression == "--zstd" {
+		if out, err := s.target.Run(s.ctx, "command -v zstd"); err != nil {
+			return &BackupServiceError{Message: "zstd is not installed", Reason: string(out)}
+		}
+	}
+
+	components, err := s.__backupComponents(opts.Components)
+	if err != nil {
+		return err
+	}
+
+	var cfg *FinchConfig
+	if err := s.tracker.Step("backup.read-finch-config", func() (err error) {
+		cfg, err = s.__backupReadFinchConfig()
+		return err
+	}); err != nil {
+		return err
+	}
+
+	var images []string
+	if err := s.tracker.Step("backup.read-images", func() (err error) {
+		images, err = s.__backupImages()
+		return err
+	}); err != nil {
+		return err
+	}
+
+	paths, services := backupPaths(components)
+
+	// Stop the services writing to the archived paths, so the archive is
+	// consistent. They are started again once the archive is written.
+	compose := "sudo docker compose --file " + path.Join(s.libDir(), "docker-compose.yaml")
+	if err := s.tracker.Step("backup.stop-services", func() error {
+		if out, err := s.target.Run(s.ctx, compose+" stop "+strings.Join(services, " ")); err != nil {
+			return &BackupServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	stopped := true
+	start := func() error {
+		stopped = false
+		if out, err := s.target.Run(s.ctx, compose+" start "+strings.Join(services, " ")); err != nil {
+			return &BackupServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+		return nil
+	}
+	defer func() {
+		if stopped {
+			_ = start()
+		}
+	}()
+
+	var checksums map[string]string
+	if err := s.tracker.Step("backup.checksum-files", func() (err error) {
+		checksums, paths, err = s.__backupChecksums(paths)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// The archive is staged in the lib directory, /tmp is often a tmpfs too
+	// small to hold the data. The staging directory is not among the
+	// archived paths.
+	raw, err := s.target.Run(s.ctx, "sudo mktemp -p "+s.libDir()+" -d .backup-XXXXXX")
+	if err != nil {
+		return &BackupServiceError{Message: err.Error(), Reason: string(raw), Err: err}
+	}
+	dir := strings.TrimSpace(string(raw))
+	if s.dryRun {
+		dir = target.DryRunTmpDir
+	}
+	defer func() {
+		_, _ = s.target.Run(s.ctx, "sudo rm -rf "+dir)
+	}()
+
+	manifest := &BackupManifest{
+		Version:    1,
+		CreatedAt:  time.Now().Format(time.RFC3339),
+		StackID:    cfg.Id,
+		Hostname:   cfg.Hostname,
+		Release:    version.Release(),
+		Components: components,
+		Images:     images,
+		Checksums:  checksums,
+	}
+	if err := s.tracker.Step("backup.write-manifest", func() error {
+		return s.__backupWriteManifest(dir, manifest)
+	}); err != nil {
+		return err
+	}
+
+	archive := path.Join(dir, path.Base(opts.Output))
+	if err := s.tracker.Step("backup.create-archive", func() error {
+		cmd := fmt.Sprintf("sudo tar %s -cf %s -C %s %s -C %s %s", compression, archive, dir, backupManifestFile, s.libDir(), strings.Join(paths, " "))
+		if out, err := s.target.Run(s.ctx, strings.Join(strings.Fields(cmd), " ")); err != nil {
+			return &BackupServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("backup.start-services", start); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("backup.download-archive", func() error {
+		if out, err := s.target.Fetch(s.ctx, archive, opts.Output); err != nil {
+			return &BackupServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type bundleEntry struct {
+	path    string
+	mode    int64
+	uid     int
+	gid     int
+	dir     bool
+	content []byte
+}
+
+type bundle struct {
+	root    string
+	entries []bundleEntry
+}
+
+func newBundle(root string) *bundle {
+	return &bundle{root: root}
+}
+
+func (b *bundle) addDir(dirPath, owner string) error {
+	return b.add(dirPath, "755", owner, true, nil)
+}
+
+func (b *bundle) addFile(filePath, mode, owner string, content []byte) error {
+	return b.add(filePath, mode, owner, false, content)
+}
+
+func (b *bundle) add(p, mode, owner string, dir bool, content []byte) error {
+	rel, ok := strings.CutPrefix(path.Clean(p), b.root+"/")
+	if !ok {
+		return fmt.Errorf("path %s is outside of %s", p, b.root)
+	}
+
+	m, err := strconv.ParseInt(mode, 8, 64)
+	if err != nil {
+		return fmt.Errorf("invalid mode %s for %s", mode, p)
+	}
+
+	uid, gid, err := parseOwner(owner)
+	if err != nil {
+		return fmt.Errorf("invalid owner %s for %s", owner, p)
+	}
+
+	b.entries = append(b.entries, bundleEntry{
+		path:    rel,
+		mode:    m,
+		uid:     uid,
+		gid:     gid,
+		dir:     dir,
+		content: content,
+	})
+
+	return nil
+}
+
+func (b *bundle) write(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	now := time.Now()
+
+	for _, e := range b.entries {
+		hdr := &tar.Header{
+			Name:    e.path,
+			Mode:    e.mode,
+			Uid:     e.uid,
+			Gid:     e.gid,
+			ModTime: now,
+			Format:  tar.FormatPAX,
+		}
+		if e.dir {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		} else {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(e.content))
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(e.content); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func parseOwner(owner string) (int, int, error) {
+	uid, gid, ok := strings.Cut(owner, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid owner %s", owner)
+	}
+
+	u, err := strconv.Atoi(uid)
+	if err != nil {
+		return 0, 0, err
+	}
+	g, err := strconv.Atoi(gid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return u, g, nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT License, see LICENSE file in the project root for details.
+*/
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BundleWritesEntriesWithModeAndOwner(t *testing.T) {
+	b := newBundle("/var/lib/finch")
+
+	err := b.addDir("/var/lib/finch/loki/etc", "10001:10001")
+	assert.NoError(t, err, "add directory")
+	err = b.addFile("/var/lib/finch/loki/etc/loki.yaml", "400", "10001:10001", []byte("auth_enabled: false\n"))
+	assert.NoError(t, err, "add file")
+
+	err = b.addFile("/etc/docker/daemon.json", "400", "0:0", nil)
+	assert.ErrorContains(t, err, "outside of /var/lib/finch", "path outside of root")
+	err = b.addFile("/var/lib/finch/finch.json", "400", "root", nil)
+	assert.ErrorContains(t, err, "invalid owner", "symbolic owner")
+
+	var buf bytes.Buffer
+	err = b.write(&buf)
+	assert.NoError(t, err, "write bundle")
+
+	tr := tar.NewReader(&buf)
+
+	hdr, err := tr.Next()
+	assert.NoError(t, err, "read directory header")
+	assert.Equal(t, "loki/etc/", hdr.Name, "directory name")
+	assert.Equal(t, byte(tar.TypeDir), hdr.Typeflag, "directory type")
+	assert.Equal(t, int64(0755), hdr.Mode, "directory mode")
+	assert.Equal(t, 10001, hdr.Uid, "directory uid")
+
+	hdr, err = tr.Next()
+	assert.NoError(t, err, "read file header")
+	assert.Equal(t, "loki/etc/loki.yaml", hdr.Name, "file name")
+	assert.Equal(t, int64(0400), hdr.Mode, "file mode")
+	assert.Equal(t, 10001, hdr.Gid, "file gid")
+	content, _ := io.ReadAll(tr)
+	assert.Equal(t, "auth_enabled: false\n", string(content), "file content")
+
+	_, err = tr.Next()
+	assert.ErrorIs(t, err, io.EOF, "no further entries")
+}
@@ -24,7 +24,7 @@ func (s *Service) dashboardService(sessionTimeout int32, role string, scope []st
 
 	ctx, client, err := grpc.NewClient(ctx, s.config.Hostname, api.NewDashboardServiceClient)
 	if err != nil {
-		return nil, &DashboardServiceError{Message: err.Error(), Reason: ""}
+		return nil, &DashboardServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = client.Close()
@@ -36,7 +36,7 @@ func (s *Service) dashboardService(sessionTimeout int32, role string, scope []st
 		Scope:          scope,
 	})
 	if err != nil {
-		return nil, &DashboardServiceError{Message: err.Error(), Reason: ""}
+		return nil, &DashboardServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	return &DashboardData{
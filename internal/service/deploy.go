@@ -12,9 +12,11 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"os/user"
 	"path"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
@@ -31,59 +33,38 @@ const (
 	finchProfiler           = "http://pyroscope:4040"
 )
 
-func (s *Service) __deployMakeDirHierarchy() error {
-	directories := []string{
-		"grafana/dashboards",
-		"grafana/alerting",
-		"loki/data",
-		"loki/etc",
-		"alloy/data",
-		"alloy/etc",
-		"traefik/etc/certs.d",
-		"traefik/etc/conf.d",
-		"mimir/data",
-		"mimir/etc",
-		"pyroscope/data",
-		"pyroscope/etc",
-	}
-	for _, dir := range directories {
-		out, err := s.target.Run(s.ctx, "sudo mkdir -p "+path.Join(s.libDir(), dir))
-		if err != nil {
-			return &DeployServiceError{Message: err.Error(), Reason: string(out)}
-		}
-	}
+var (
+	readinessMaxWait  = 180 * time.Second
+	readinessInterval = 2 * time.Second
+)
 
-	return nil
+// dirHierarchy maps the directories below the lib directory to their owner.
+var dirHierarchy = map[string]string{
+	"grafana":             "472:472",
+	"grafana/dashboards":  "472:472",
+	"grafana/alerting":    "472:472",
+	"loki":                "10001:10001",
+	"loki/data":           "10001:10001",
+	"loki/etc":            "10001:10001",
+	"alloy":               "0:0",
+	"alloy/data":          "0:0",
+	"alloy/etc":           "0:0",
+	"traefik":             "0:0",
+	"traefik/etc":         "0:0",
+	"traefik/etc/certs.d": "0:0",
+	"traefik/etc/conf.d":  "0:0",
+	"mimir":               "10001:10001",
+	"mimir/data":          "10001:10001",
+	"mimir/etc":           "10001:10001",
+	"pyroscope":           "10001:10001",
+	"pyroscope/data":      "10001:10001",
+	"pyroscope/etc":       "10001:10001",
 }
 
-func (s *Service) __deploySetDirHierarchyPermission() error {
-	ownership := map[string]string{
-		"grafana":             "472:472",
-		"grafana/dashboards":  "472:472",
-		"grafana/alerting":    "472:472",
-		"loki":                "10001:10001",
-		"loki/data":           "10001:10001",
-		"loki/etc":            "10001:10001",
-		"alloy":               "0:0",
-		"alloy/data":          "0:0",
-		"alloy/etc":           "0:0",
-		"traefik":             "0:0",
-		"traefik/etc":         "0:0",
-		"traefik/etc/certs.d": "0:0",
-		"traefik/etc/conf.d":  "0:0",
-		"mimir":               "10001:10001",
-		"mimir/data":          "10001:10001",
-		"mimir/etc":           "10001:10001",
-		"pyroscope":           "10001:10001",
-		"pyroscope/data":      "10001:10001",
-		"pyroscope/etc":       "10001:10001",
-	}
-
-	for dir, owner := range ownership {
-		cmd := fmt.Sprintf("sudo chown %s %s", owner, path.Join(s.libDir(), dir))
-		out, err := s.target.Run(s.ctx, cmd)
-		if err != nil {
-			return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+func (s *Service) __deployMakeDirHierarchy() error {
+	for _, dir := range slices.Sorted(maps.Keys(dirHierarchy)) {
+		if err := s.bundle.addDir(path.Join(s.libDir(), dir), dirHierarchy[dir]); err != nil {
+			return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 		}
 	}
 
@@ -92,7 +73,7 @@ func (s *Service) __deploySetDirHierarchyPermission() error {
 
 func (s *Service) __deployCopyLokiConfig() error {
 	path := path.Join(s.libDir(), "loki/etc/loki.yaml")
-	return s.__helperCopyConfig(path, "400", "10001:10001")
+	return s.__helperCopyTemplate(path, "400", "10001:10001", s.__retention())
 }
 
 func (s *Service) __deployCopyTraefikConfig() error {
@@ -142,18 +123,27 @@ func (s *Service) __deployCopyTraefikHttpTlsConfig() error {
 	}
 
 	if s.config.CustomTLS.Enabled {
+		cert, key := s.config.CustomTLS.CertFilePath, s.config.CustomTLS.KeyFilePath
+		if cert == "" && key == "" {
+			s.__helperPrintProgress("Skipping copying custom TLS, keeping the deployed pair")
+			return nil
+		}
+		if _, err := validateTLSPair(cert, key, s.config.Hostname); err != nil {
+			return &DeployServiceError{Message: "invalid custom TLS files", Reason: err.Error(), Err: err}
+		}
+
 		assets := map[string]string{
-			"cert": s.config.CustomTLS.CertFilePath,
-			"key":  s.config.CustomTLS.KeyFilePath,
+			"cert": cert,
+			"key":  key,
 		}
 		for k, v := range assets {
-			if _, err := os.Stat(v); err != nil {
-				s.__helperPrintProgress(fmt.Sprintf("Skipping copying custom TLS %s", k))
-				continue
+			content, err := os.ReadFile(v)
+			if err != nil {
+				return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 			}
 			pem := path.Join(s.libDir(), "traefik/etc/certs.d", k+".pem")
-			if out, err := s.target.Copy(s.ctx, v, pem, "400", "0:0"); err != nil {
-				return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+			if err := s.__helperCopyContent(pem, "400", "0:0", content); err != nil {
+				return err
 			}
 		}
 	}
@@ -173,20 +163,13 @@ func (s *Service) __deployGenerateMTLSCertificates() error {
 	}
 
 	caCertPath := path.Join(s.libDir(), "traefik/etc/certs.d", version.ResourceID()+".pem")
-	f, err := os.CreateTemp("", "ca.pem")
-	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
-	}
-	defer func() {
-		_ = os.Remove(f.Name())
-	}()
-	if _, err := f.Write(caCertPEM); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
-	}
-	if out, err := s.target.Copy(s.ctx, f.Name(), caCertPath, "400", "0:0"); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+	if err := s.__helperCopyContent(caCertPath, "400", "0:0", caCertPEM); err != nil {
+		return err
 	}
 
+	if s.planOut != "" {
+		return s.__deployExportCredentials(clientCertPEM, clientKeyPEM)
+	}
 	if s.dryRun {
 		return nil
 	}
@@ -198,6 +181,31 @@ func (s *Service) __deployGenerateMTLSCertificates() error {
 	return nil
 }
 
+// __deployExportCredentials writes the client credentials of a plan next to
+// the plan script instead of the local config. The target trusts them only
+// once the plan has run, they are imported then.
+func (s *Service) __deployExportCredentials(certPEM, keyPEM []byte) error {
+	file := s.planOut + ".credentials.json"
+	if err := config.ExportStack(file, s.config.Hostname, certPEM, keyPEM); err != nil {
+		return &DeployServiceError{Message: "failed to export stack certificates", Reason: err.Error()}
+	}
+
+	note := fmt.Sprintf("After running the plan, import the client credentials with 'finchctl service import-credentials %s'", file)
+	f, err := os.OpenFile(s.planOut, os.O_APPEND|os.O_WRONLY, 0755)
+	if err != nil {
+		return &DeployServiceError{Message: "failed to write plan", Reason: err.Error()}
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, err := fmt.Fprintf(f, "# %s.\n", note); err != nil {
+		return &DeployServiceError{Message: "failed to write plan", Reason: err.Error()}
+	}
+	fmt.Fprintf(os.Stderr, "%s.\n", note)
+
+	return nil
+}
+
 func (s *Service) __deployCopyAlloyConfig() error {
 	path := path.Join(s.libDir(), "alloy/etc/alloy.config")
 
@@ -215,7 +223,7 @@ func (s *Service) __deployCopyFinchConfig() error {
 
 	key := make([]byte, 32)
 	if _, err := rand.Read(key); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 	secret := base64.StdEncoding.EncodeToString(key)
 
@@ -260,12 +268,12 @@ func (s *Service) __deployCopyGrafanaAlerts() error {
 
 func (s *Service) __deployCopyMimirConfig() error {
 	path := path.Join(s.libDir(), "mimir/etc/mimir.yaml")
-	return s.__helperCopyConfig(path, "400", "10001:10001")
+	return s.__helperCopyTemplate(path, "400", "10001:10001", s.__retention())
 }
 
 func (s *Service) __deployCopyPyroscopeConfig() error {
 	path := path.Join(s.libDir(), "pyroscope/etc/pyroscope.yaml")
-	return s.__helperCopyConfig(path, "400", "10001:10001")
+	return s.__helperCopyTemplate(path, "400", "10001:10001", s.__retention())
 }
 
 func (s *Service) __helperCopyConfig(filePath, mode, owner string) error {
@@ -273,54 +281,124 @@ func (s *Service) __helperCopyConfig(filePath, mode, owner string) error {
 
 	content, err := fs.ReadFile(Assets, fileName)
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	return s.__helperCopyContent(filePath, mode, owner, content)
+}
+
+func (s *Service) __helperCopyTemplate(filePath, mode, owner string, data any) error {
+	content, err := s.__helperRenderTemplate(path.Base(filePath), data)
+	if err != nil {
+		return err
+	}
+
+	return s.__helperCopyContent(filePath, mode, owner, content)
+}
+
+func (s *Service) __helperRenderTemplate(fileName string, data any) ([]byte, error) {
+	tmpl, err := template.New(fileName+".tmpl").ParseFS(Assets, fileName+".tmpl")
+	if err != nil {
+		return nil, &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
-	f, err := os.CreateTemp("", fileName)
+	buf := new(bytes.Buffer)
+	err = tmpl.Execute(buf, data)
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return nil, &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *Service) __helperCopyContent(filePath, mode, owner string, content []byte) error {
+	if s.bundle != nil {
+		if err := s.bundle.addFile(filePath, mode, owner, content); err != nil {
+			return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
+		}
+		return nil
+	}
+
+	f, err := os.CreateTemp("", path.Base(filePath))
+	if err != nil {
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = os.Remove(f.Name())
 	}()
 	if _, err := f.Write(content); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	if out, err := s.target.Copy(s.ctx, f.Name(), filePath, mode, owner); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
 }
 
-func (s *Service) __helperCopyTemplate(filePath, mode, owner string, data any) error {
-	fileName := path.Base(filePath)
-
-	tmpl, err := template.New(fileName+".tmpl").ParseFS(Assets, fileName+".tmpl")
+func (s *Service) __helperFetchFile(filePath string) ([]byte, error) {
+	f, err := os.CreateTemp("", path.Base(filePath))
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return nil, &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
+	_ = f.Close()
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
 
-	buf := new(bytes.Buffer)
-	err = tmpl.Execute(buf, data)
+	if out, err := s.target.Fetch(s.ctx, filePath, f.Name()); err != nil {
+		return nil, &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	content, err := os.ReadFile(f.Name())
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return nil, &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
-	f, err := os.CreateTemp("", fileName)
+	return content, nil
+}
+
+func (s *Service) __helperUploadBundle() error {
+	defer func() {
+		s.bundle = nil
+	}()
+
+	f, err := os.CreateTemp("", "finch-bundle-*.tar")
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = os.Remove(f.Name())
 	}()
-	if _, err := f.WriteString(buf.String()); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+
+	s.__helperPrintProgress(fmt.Sprintf("Bundling %d assets into '%s'", len(s.bundle.entries), f.Name()))
+	if err := s.bundle.write(f); err != nil {
+		_ = f.Close()
+		return &DeployServiceError{Message: "failed to write asset bundle", Reason: err.Error()}
+	}
+	if err := f.Close(); err != nil {
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
-	if out, err := s.target.Copy(s.ctx, f.Name(), filePath, mode, owner); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+	raw, err := s.target.Run(target.Idempotent(s.ctx), "mktemp -p /tmp -d finch-XXXXXX")
+	if err != nil {
+		return &DeployServiceError{Message: err.Error(), Reason: string(raw), Err: err}
+	}
+	dir := strings.TrimSpace(string(raw))
+	if s.dryRun {
+		dir = target.DryRunTmpDir
+	}
+
+	dest := path.Join(dir, "bundle.tar")
+	if out, err := s.target.Copy(s.ctx, f.Name(), dest, "400", "0:0"); err != nil {
+		_, _ = s.target.Run(s.ctx, "rm -rf "+dir)
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	unpack := fmt.Sprintf("sudo mkdir -p %s && sudo tar -xpf %s -C %s; rc=$?; sudo rm -rf %s; exit $rc", s.libDir(), dest, s.libDir(), dir)
+	if out, err := s.target.Run(target.Idempotent(s.ctx), unpack); err != nil {
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -349,28 +427,28 @@ func (s *Service) __deployCopyComposeFile() error {
 
 	alloyTmplBytes, err := fs.ReadFile(Assets, "alloy.config.tmpl")
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 	alloyTmpl, err := template.New("alloy").Parse(string(alloyTmplBytes))
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 	var alloyRendered bytes.Buffer
 	if err = alloyTmpl.Execute(&alloyRendered, struct{ Hostname string }{s.config.Hostname}); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
-	lokiBytes, err := fs.ReadFile(Assets, "loki.yaml")
+	lokiBytes, err := s.__helperRenderTemplate("loki.yaml", s.__retention())
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return err
 	}
-	mimirBytes, err := fs.ReadFile(Assets, "mimir.yaml")
+	mimirBytes, err := s.__helperRenderTemplate("mimir.yaml", s.__retention())
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return err
 	}
-	pyroscopeBytes, err := fs.ReadFile(Assets, "pyroscope.yaml")
+	pyroscopeBytes, err := s.__helperRenderTemplate("pyroscope.yaml", s.__retention())
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return err
 	}
 
 	grafanaAssets := []string{
@@ -385,13 +463,14 @@ func (s *Service) __deployCopyComposeFile() error {
 	for _, name := range grafanaAssets {
 		b, err := fs.ReadFile(Assets, name)
 		if err != nil {
-			return &DeployServiceError{Message: err.Error(), Reason: ""}
+			return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 		}
 		grafanaChunks = append(grafanaChunks, b)
 	}
 
 	data := struct {
 		RootUrl             string
+		Images              map[string]string
 		AlloyConfigHash     string
 		GrafanaConfigHash   string
 		LokiConfigHash      string
@@ -399,6 +478,7 @@ func (s *Service) __deployCopyComposeFile() error {
 		PyroscopeConfigHash string
 	}{
 		RootUrl:             fmt.Sprintf("https://%s", s.config.Hostname),
+		Images:              s.__images(),
 		AlloyConfigHash:     s.__configHash(alloyRendered.Bytes()),
 		GrafanaConfigHash:   s.__configHash(grafanaChunks...),
 		LokiConfigHash:      s.__configHash(lokiBytes),
@@ -410,9 +490,9 @@ func (s *Service) __deployCopyComposeFile() error {
 }
 
 func (s *Service) __deployComposeUp() error {
-	out, err := s.target.Run(s.ctx, "sudo docker compose --file "+path.Join(s.libDir(), "docker-compose.yaml")+" up --detach")
+	out, err := s.target.Run(target.Idempotent(s.ctx), "sudo docker compose --file "+path.Join(s.libDir(), "docker-compose.yaml")+" up --detach")
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -433,14 +513,10 @@ func (s *Service) __deployComposeReady() error {
 		"hc-pyroscope",
 	}
 
-	const (
-		maxWait        = 180 * time.Second
-		interval       = 2 * time.Second
-		unhealthyLimit = 5
-	)
+	const unhealthyLimit = 5
 
 	unhealthyCount := make(map[string]int)
-	deadline := time.Now().Add(maxWait)
+	deadline := time.Now().Add(readinessMaxWait)
 	for time.Now().Before(deadline) {
 		allHealthy := true
 		var failed []string
@@ -480,7 +556,7 @@ func (s *Service) __deployComposeReady() error {
 			return nil
 		}
 
-		time.Sleep(interval)
+		time.Sleep(readinessInterval)
 	}
 
 	var timedOut []string
@@ -513,67 +589,81 @@ func (s *Service) __deployCollectHealthLogs(containers []string) *[]string {
 }
 
 func (s *Service) deployService() error {
-	if err := s.__deployMakeDirHierarchy(); err != nil {
+	s.bundle = newBundle(s.libDir())
+
+	if err := s.tracker.Step("deploy.make-dir-hierarchy", s.__deployMakeDirHierarchy); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("deploy.copy-loki-config", s.__deployCopyLokiConfig); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("deploy.copy-traefik-config", s.__deployCopyTraefikConfig); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("deploy.copy-traefik-http-config", s.__deployCopyTraefikHttpConfig); err != nil {
 		return err
 	}
 
-	if err := s.__deploySetDirHierarchyPermission(); err != nil {
+	if err := s.tracker.Step("deploy.copy-traefik-http-tls-config", s.__deployCopyTraefikHttpTlsConfig); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyLokiConfig(); err != nil {
+	if err := s.tracker.Step("deploy.generate-mtls-certificates", s.__deployGenerateMTLSCertificates); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyTraefikConfig(); err != nil {
+	if err := s.tracker.Step("deploy.copy-alloy-config", s.__deployCopyAlloyConfig); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyTraefikHttpConfig(); err != nil {
+	if err := s.tracker.Step("deploy.copy-grafana-dashboards", s.__deployCopyGrafanaDashboards); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyTraefikHttpTlsConfig(); err != nil {
+	if err := s.tracker.Step("deploy.copy-grafana-alerts", s.__deployCopyGrafanaAlerts); err != nil {
 		return err
 	}
 
-	if err := s.__deployGenerateMTLSCertificates(); err != nil {
+	if err := s.tracker.Step("deploy.copy-finch-config", s.__deployCopyFinchConfig); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyAlloyConfig(); err != nil {
+	if err := s.tracker.Step("deploy.copy-mimir-config", s.__deployCopyMimirConfig); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyGrafanaDashboards(); err != nil {
+	if err := s.tracker.Step("deploy.copy-pyroscope-config", s.__deployCopyPyroscopeConfig); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyGrafanaAlerts(); err != nil {
+	if err := s.tracker.Step("deploy.pin-image-digests", s.__deployPinImageDigests); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyFinchConfig(); err != nil {
+	if err := s.tracker.Step("deploy.copy-compose-file", s.__deployCopyComposeFile); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyMimirConfig(); err != nil {
+	if err := s.tracker.Step("deploy.write-stack-manifest", s.__stackWriteManifest); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyPyroscopeConfig(); err != nil {
+	if err := s.tracker.Step("deploy.upload-bundle", s.__helperUploadBundle); err != nil {
 		return err
 	}
 
-	if err := s.__deployCopyComposeFile(); err != nil {
+	if err := s.tracker.Step("deploy.load-images", s.__offlineLoadImages); err != nil {
 		return err
 	}
 
-	if err := s.__deployComposeUp(); err != nil {
+	if err := s.tracker.Step("deploy.compose-up", s.__deployComposeUp); err != nil {
 		return err
 	}
 
-	if err := s.__deployComposeReady(); err != nil {
+	if err := s.tracker.Step("deploy.compose-ready", s.__deployComposeReady); err != nil {
 		return err
 	}
 
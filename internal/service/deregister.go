@@ -16,13 +16,13 @@ func (s *Service) deregisterService(clientID string, keepCfg bool) error {
 	}
 
 	if _, err := config.LookupStack(s.config.Hostname); err != nil {
-		return &DeregisterServiceError{Message: err.Error(), Reason: ""}
+		return &DeregisterServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	caCertPath := path.Join(s.libDir(), "traefik/etc/certs.d", clientID+".pem")
 	out, err := s.target.Run(s.ctx, "rm -f "+caCertPath)
 	if err != nil {
-		return &DeregisterServiceError{Message: err.Error(), Reason: string(out)}
+		return &DeregisterServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	if s.dryRun {
@@ -30,7 +30,7 @@ func (s *Service) __dockerComposeIsAvailable() bool {
 func (s *Service) __dockerInstallService() error {
 	raw, err := s.target.Run(s.ctx, "mktemp -p /tmp -d finch-XXXXXX")
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 	dir := strings.TrimSpace(string(raw))
 	defer func() {
@@ -41,12 +41,12 @@ func (s *Service) __dockerInstallService() error {
 
 	out, err := s.target.Run(s.ctx, "curl -fsSL https://get.docker.com -o "+dir+"/get-docker.sh")
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = s.target.Run(s.ctx, "sudo sh "+dir+"/get-docker.sh")
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -57,26 +57,26 @@ func (s *Service) __dockerCopyConfig() error {
 
 	content, err := fs.ReadFile(Assets, "daemon.json")
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	f, err := os.CreateTemp("", "daemon.json")
 	if err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = os.Remove(f.Name())
 	}()
 	if _, err := f.Write(content); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: ""}
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	if out, err := s.target.Copy(s.ctx, f.Name(), dest, "400", "0:0"); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	if out, err := s.target.Run(s.ctx, "sudo systemctl restart docker"); err != nil {
-		return &DeployServiceError{Message: err.Error(), Reason: string(out)}
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -84,19 +84,28 @@ func (s *Service) __dockerCopyConfig() error {
 
 func (s *Service) dockerService() error {
 	if !s.__dockerIsAvailable() {
-		if err := s.__dockerInstallService(); err != nil {
+		install := s.__dockerInstallService
+		if s.offline {
+			install = s.__dockerRequireInstalled
+		}
+		if err := s.tracker.Step("docker.install-service", install); err != nil {
 			return err
 		}
 	}
 
-	if !s.__dockerIsRunning() {
-		return &DeployServiceError{Message: "Docker is not running", Reason: ""}
-	}
-	if !s.__dockerComposeIsAvailable() {
-		return &DeployServiceError{Message: "Docker Compose is not available", Reason: ""}
+	if err := s.tracker.Step("docker.is-running", func() error {
+		if !s.__dockerIsRunning() {
+			return &DeployServiceError{Message: "Docker is not running", Reason: ""}
+		}
+		if !s.__dockerComposeIsAvailable() {
+			return &DeployServiceError{Message: "Docker Compose is not available", Reason: ""}
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
-	if err := s.__dockerCopyConfig(); err != nil {
+	if err := s.tracker.Step("docker.copy-config", s.__dockerCopyConfig); err != nil {
 		return err
 	}
 
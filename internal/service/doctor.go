@@ -5,6 +5,8 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package service
 
 import (
+	"fmt"
+
 	"github.com/fatih/color"
 )
 
@@ -37,7 +39,9 @@ func (s *Service) __examineRequirements() (*[]Health, bool) {
 		list = append(list, Health{r, t, false, o})
 	}
 
-	verify(s.__requirementsHasSudo, "sudo", "available", "not available")
+	if tool := s.escalation.Tool(); tool != "" {
+		verify(s.__requirementsHasSudo, tool, "available", "not available")
+	}
 	verify(s.__requirementsHasSudoPermission, "superuser permission", "sufficient", "insufficient")
 	verify(s.__requirementsHasCurl, "curl", "available", "not available")
 	verify(s.__requirementsGitHubConnection, "GitHub connection", "established", "not established")
@@ -48,11 +52,10 @@ func (s *Service) __examineRequirements() (*[]Health, bool) {
 func (s *Service) __examinePorts() (*[]Health, bool) {
 	var list []Health
 
-	var port string
 	var status string
 
 	cmd := "ss"
-	exec := "sudo ss -H -tlpn sport = :" + port + " | grep -q ''"
+	exec := "sudo ss -H -tlpn sport = :%s | grep -q ''"
 
 	if _, err := s.target.Run(s.ctx, "command -v "+cmd); err != nil {
 		list = append(list, Health{"port check", color.RedString(cmd + " not found"), false, false})
@@ -67,7 +70,7 @@ func (s *Service) __examinePorts() (*[]Health, bool) {
 
 	for port, optional := range ports {
 		o := true
-		_, err := s.target.Run(s.ctx, exec)
+		_, err := s.target.Run(s.ctx, fmt.Sprintf(exec, port))
 		if err == nil {
 			o = false
 			status = color.RedString("bound")
@@ -13,84 +13,213 @@ import (
 type DeployServiceError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *DeployServiceError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to deploy service: %s %s", e.Message, e.Reason))
 }
 
+func (e *DeployServiceError) Unwrap() error {
+	return e.Err
+}
+
 type TeardownServiceError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *TeardownServiceError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to teardown service: %s %s", e.Message, e.Reason))
 }
 
+func (e *TeardownServiceError) Unwrap() error {
+	return e.Err
+}
+
 type UpdateServiceError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *UpdateServiceError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to update service: %s %s", e.Message, e.Reason))
 }
 
+func (e *UpdateServiceError) Unwrap() error {
+	return e.Err
+}
+
 type InfoServiceError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *InfoServiceError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to get service info: %s %s", e.Message, e.Reason))
 }
 
+func (e *InfoServiceError) Unwrap() error {
+	return e.Err
+}
+
 type DashboardServiceError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *DashboardServiceError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to get dashboard token: %s %s", e.Message, e.Reason))
 }
 
+func (e *DashboardServiceError) Unwrap() error {
+	return e.Err
+}
+
 type RotateServiceCertificateError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *RotateServiceCertificateError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to rotate service certificates: %s %s", e.Message, e.Reason))
 }
 
+func (e *RotateServiceCertificateError) Unwrap() error {
+	return e.Err
+}
+
+type RotateServiceTLSError struct {
+	Message string
+	Reason  string
+	Err     error
+}
+
+func (e *RotateServiceTLSError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("Failed to rotate service TLS certificate: %s %s", e.Message, e.Reason))
+}
+
+func (e *RotateServiceTLSError) Unwrap() error {
+	return e.Err
+}
+
 type RegisterServiceError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *RegisterServiceError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to register service: %s %s", e.Message, e.Reason))
 }
 
+func (e *RegisterServiceError) Unwrap() error {
+	return e.Err
+}
+
 type DeregisterServiceError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *DeregisterServiceError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to deregister service: %s %s", e.Message, e.Reason))
 }
 
+func (e *DeregisterServiceError) Unwrap() error {
+	return e.Err
+}
+
 type RotateServiceSecretError struct {
 	Message string
 	Reason  string
+	Err     error
 }
 
 func (e *RotateServiceSecretError) Error() string {
 	return strings.TrimSpace(fmt.Sprintf("Failed to rotate service secret: %s %s", e.Message, e.Reason))
 }
 
+func (e *RotateServiceSecretError) Unwrap() error {
+	return e.Err
+}
+
+type BackupServiceError struct {
+	Message string
+	Reason  string
+	Err     error
+}
+
+func (e *BackupServiceError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("Failed to backup service: %s %s", e.Message, e.Reason))
+}
+
+func (e *BackupServiceError) Unwrap() error {
+	return e.Err
+}
+
+type RestoreServiceError struct {
+	Message string
+	Reason  string
+	Err     error
+}
+
+func (e *RestoreServiceError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("Failed to restore service: %s %s", e.Message, e.Reason))
+}
+
+func (e *RestoreServiceError) Unwrap() error {
+	return e.Err
+}
+
+type ImagesServiceError struct {
+	Message string
+	Reason  string
+	Err     error
+}
+
+func (e *ImagesServiceError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("Failed to list service images: %s %s", e.Message, e.Reason))
+}
+
+func (e *ImagesServiceError) Unwrap() error {
+	return e.Err
+}
+
+type StatusServiceError struct {
+	Message string
+	Reason  string
+	Err     error
+}
+
+func (e *StatusServiceError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("Failed to get service status: %s %s", e.Message, e.Reason))
+}
+
+func (e *StatusServiceError) Unwrap() error {
+	return e.Err
+}
+
+type LogsServiceError struct {
+	Message string
+	Reason  string
+	Err     error
+}
+
+func (e *LogsServiceError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("Failed to stream service logs: %s %s", e.Message, e.Reason))
+}
+
+func (e *LogsServiceError) Unwrap() error {
+	return e.Err
+}
+
 func convertError(err error, to any) error {
 	if err == nil {
 		return nil
@@ -120,6 +249,12 @@ func convertError(err error, to any) error {
 		toField.SetString(f.String())
 	}
 
+	f := elem.FieldByName("Err")
+	toField := reflect.ValueOf(to).Elem().FieldByName("Err")
+	if f.IsValid() && toField.IsValid() && toField.CanSet() && f.Type() == toField.Type() {
+		toField.Set(f)
+	}
+
 	if e, ok := to.(error); ok {
 		return e
 	}
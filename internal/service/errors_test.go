@@ -5,9 +5,11 @@ Licensed under the MIT License, see LICENSE file in the project root for details
 package service
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/finchctl/internal/target"
 )
 
 func raiseError() error {
@@ -31,3 +33,12 @@ func Test_ConvertError(t *testing.T) {
 	err = convertError(err, nil)
 	assert.Nil(t, err, "expected nil when target error type is nil")
 }
+
+func Test_ConvertErrorKeepsExitStatus(t *testing.T) {
+	cause := &target.ExitError{Status: 17, Err: errors.New("Process exited with status 17")}
+	err := convertError(&DeployServiceError{Message: cause.Error(), Reason: "", Err: cause}, &UpdateServiceError{})
+
+	var exitErr *target.ExitError
+	assert.ErrorAs(t, err, &exitErr, "expected the exit error to be preserved")
+	assert.Equal(t, 17, exitErr.Status, "exit status")
+}
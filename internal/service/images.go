@@ -0,0 +1,188 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// An image override of "default" selects the image of the release.
+const defaultImage = "default"
+
+// DefaultImages maps the components of the stack to the images shipped with
+// this release.
+var DefaultImages = map[string]string{
+	"alloy":     "grafana/alloy:v1.18.0",
+	"curl":      "curlimages/curl:8.21.0",
+	"finch":     "ghcr.io/tschaefer/finch:1.13.1",
+	"grafana":   "grafana/grafana:13.1.1",
+	"loki":      "grafana/loki:3.7.4",
+	"mimir":     "grafana/mimir:3.1.4",
+	"pyroscope": "grafana/pyroscope:2.2.0",
+	"traefik":   "traefik:v3.7.10",
+}
+
+// imageContainers maps the components to a container running their image,
+// curl runs the health checks.
+var imageContainers = map[string]string{
+	"alloy":     "alloy",
+	"curl":      "hc-traefik",
+	"finch":     "finch",
+	"grafana":   "grafana",
+	"loki":      "loki",
+	"mimir":     "mimir",
+	"pyroscope": "pyroscope",
+	"traefik":   "traefik",
+}
+
+type ImageData struct {
+	Component string `json:"component"`
+	Image     string `json:"image"`
+	Source    string `json:"source"`
+	Running   string `json:"running"`
+}
+
+// ParseImages returns the image overrides of the YAML file, mapping
+// components to images, and of the specs given as component=image. The specs
+// take precedence.
+func ParseImages(specs []string, file string) (map[string]string, error) {
+	images := map[string]string{}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image file: %w", err)
+		}
+		if err := yaml.UnmarshalWithOptions(data, &images, yaml.Strict()); err != nil {
+			return nil, fmt.Errorf("failed to parse image file %s: %w", file, err)
+		}
+	}
+
+	for _, spec := range specs {
+		component, image, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid image %q, use component=image", spec)
+		}
+		images[component] = image
+	}
+
+	for component, image := range images {
+		if _, ok := DefaultImages[component]; !ok {
+			return nil, fmt.Errorf("unknown image component %s, use one of %s",
+				component, strings.Join(slices.Sorted(maps.Keys(DefaultImages)), ", "))
+		}
+		if image == "" {
+			return nil, fmt.Errorf("no image given for component %s", component)
+		}
+	}
+
+	return images, nil
+}
+
+// __unpinnedImages returns the image of each component, the release image
+// unless it is overridden.
+func (s *Service) __unpinnedImages() map[string]string {
+	images := maps.Clone(DefaultImages)
+	for component, image := range s.config.Images {
+		if _, ok := images[component]; ok && image != defaultImage {
+			images[component] = image
+		}
+	}
+
+	return images
+}
+
+// __images returns the image of each component pinned by its digest if it
+// was pinned.
+func (s *Service) __images() map[string]string {
+	images := s.__unpinnedImages()
+	for component, image := range images {
+		if pinned, ok := s.config.Pins[image]; ok {
+			images[component] = pinned
+		}
+	}
+
+	return images
+}
+
+// __deployPinImageDigests pins the image of each component by its digest.
+// The pins are keyed by the image they were resolved from, an image of a
+// newer release or a new override is pulled and pinned anew. A pinned stack
+// stays pinned.
+func (s *Service) __deployPinImageDigests() error {
+	if !s.pinDigests && (len(s.config.Pins) == 0 || s.offline) {
+		return nil
+	}
+
+	pins := map[string]string{}
+	images := s.__unpinnedImages()
+	for _, component := range slices.Sorted(maps.Keys(images)) {
+		image := images[component]
+		if strings.Contains(image, "@") {
+			continue
+		}
+		if pinned, ok := s.config.Pins[image]; ok {
+			pins[image] = pinned
+			continue
+		}
+
+		if out, err := s.target.Run(s.ctx, "sudo docker pull --quiet "+image); err != nil {
+			return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+		out, err := s.target.Run(s.ctx, fmt.Sprintf("sudo docker image inspect --format '{{index .RepoDigests 0}}' %s", image))
+		if err != nil {
+			return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+
+		_, digest, ok := strings.Cut(strings.TrimSpace(string(out)), "@")
+		if !ok {
+			if s.dryRun {
+				continue
+			}
+			return &DeployServiceError{Message: "no digest for image " + image, Reason: string(out)}
+		}
+
+		pins[image] = image + "@" + digest
+	}
+	s.config.Pins = pins
+
+	return nil
+}
+
+func (s *Service) imagesService() ([]ImageData, error) {
+	if err := s.__updateSetTargetConfiguration(); err != nil {
+		return nil, convertError(err, &ImagesServiceError{})
+	}
+
+	images := s.__images()
+	var list []ImageData
+	for _, component := range slices.Sorted(maps.Keys(images)) {
+		source := "default"
+		if image, ok := s.config.Images[component]; ok && image != defaultImage {
+			source = "override"
+		}
+
+		cmd := fmt.Sprintf("sudo docker inspect --format '{{.Config.Image}}' %s", imageContainers[component])
+		running, err := s.target.RunForce(s.ctx, cmd)
+		if err != nil {
+			running = nil
+		}
+
+		list = append(list, ImageData{
+			Component: component,
+			Image:     images[component],
+			Source:    source,
+			Running:   strings.TrimSpace(string(running)),
+		})
+	}
+
+	return list, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func Test_ParseImages(t *testing.T) {
+	file := t.TempDir() + "/images.yaml"
+	err := os.WriteFile(file, []byte("grafana: grafana/grafana:12.4.0\nloki: grafana/loki:3.8.0\n"), 0600)
+	assert.NoError(t, err, "write image file")
+
+	images, err := ParseImages([]string{"loki=grafana/loki:3.9.0", "finch=default"}, file)
+	assert.NoError(t, err, "parse images")
+	assert.Equal(t, map[string]string{
+		"grafana": "grafana/grafana:12.4.0",
+		"loki":    "grafana/loki:3.9.0",
+		"finch":   "default",
+	}, images, "specs take precedence")
+
+	_, err = ParseImages([]string{"prometheus=prom/prometheus:v3.0.0"}, "")
+	assert.ErrorContains(t, err, "unknown image component prometheus", "unknown component")
+
+	_, err = ParseImages([]string{"grafana"}, "")
+	assert.ErrorContains(t, err, "use component=image", "missing image")
+}
+
+func Test_ImagesRenderIntoComposeFile(t *testing.T) {
+	s := &Service{config: &ServiceConfig{Images: map[string]string{
+		"grafana": "grafana/grafana:12.4.0",
+		"finch":   "default",
+	}}}
+
+	images := s.__images()
+	assert.Equal(t, "grafana/grafana:12.4.0", images["grafana"], "override")
+	assert.Equal(t, DefaultImages["finch"], images["finch"], "default selects the release image")
+
+	compose, err := s.__helperRenderTemplate("docker-compose.yaml", struct {
+		RootUrl             string
+		Images              map[string]string
+		AlloyConfigHash     string
+		GrafanaConfigHash   string
+		LokiConfigHash      string
+		MimirConfigHash     string
+		PyroscopeConfigHash string
+	}{Images: images})
+	assert.NoError(t, err, "render compose file")
+	assert.Contains(t, string(compose), "image: grafana/grafana:12.4.0\n", "overridden image")
+	assert.Contains(t, string(compose), "image: "+DefaultImages["loki"]+"\n", "release image")
+}
+
+func Test_PinImageDigests(t *testing.T) {
+	bin := t.TempDir()
+	docker := "#!/bin/sh\ncase \"$*\" in *RepoDigests*) echo \"${5%%:*}@sha256:4f1c\";; esac\n"
+	err := os.WriteFile(bin+"/docker", []byte(docker), 0755)
+	assert.NoError(t, err, "write fake docker")
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+		PinDigests: true,
+		Config: &ServiceConfig{Images: map[string]string{
+			"loki": "grafana/loki:3.8.0@sha256:9e4f",
+		}},
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.__deployPinImageDigests()
+	assert.NoError(t, err, "pin image digests")
+	assert.Equal(t, "grafana/grafana:13.1.1@sha256:4f1c", s.config.Pins[DefaultImages["grafana"]], "pinned image")
+	assert.Len(t, s.config.Pins, len(DefaultImages)-1, "all images pinned")
+	assert.Len(t, s.config.Images, 1, "pins are no overrides")
+
+	images := s.__images()
+	assert.Equal(t, "grafana/grafana:13.1.1@sha256:4f1c", images["grafana"], "pinned release image")
+	assert.Equal(t, "grafana/loki:3.8.0@sha256:9e4f", images["loki"], "already pinned image")
+
+	// A pin of a previous release is dropped and the new release image
+	// pinned, without --pin-digests.
+	s.pinDigests = false
+	s.config.Pins = map[string]string{
+		"grafana/grafana:12.0.0": "grafana/grafana:12.0.0@sha256:0a1b",
+		DefaultImages["finch"]:   "ghcr.io/tschaefer/finch:1.13.1@sha256:7c2d",
+	}
+	err = s.__deployPinImageDigests()
+	assert.NoError(t, err, "re-pin image digests")
+	assert.NotContains(t, s.config.Pins, "grafana/grafana:12.0.0", "stale pin dropped")
+	assert.Equal(t, "grafana/grafana:13.1.1@sha256:4f1c", s.config.Pins[DefaultImages["grafana"]], "new release image pinned")
+	assert.Equal(t, "ghcr.io/tschaefer/finch:1.13.1@sha256:7c2d", s.config.Pins[DefaultImages["finch"]], "pin kept")
+}
+
+func Test_ImagesShowPinsAsDefault(t *testing.T) {
+	t.Setenv(ServiceLibEnv, t.TempDir())
+
+	manifest := `{ "version": 1, "config": { "hostname": "localhost",
+		"images": { "grafana": "grafana/grafana:12.4.0" },
+		"pins": { "` + DefaultImages["loki"] + `": "` + DefaultImages["loki"] + `@sha256:9e4f" } } }`
+	err := os.WriteFile(os.Getenv(ServiceLibEnv)+"/"+stackManifestFile, []byte(manifest), 0600)
+	assert.NoError(t, err, "write stack manifest")
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	list, err := s.imagesService()
+	assert.NoError(t, err, "list images")
+	for _, image := range list {
+		switch image.Component {
+		case "grafana":
+			assert.Equal(t, "override", image.Source, "explicit override")
+		case "loki":
+			assert.Equal(t, DefaultImages["loki"]+"@sha256:9e4f", image.Image, "pinned release image")
+			assert.Equal(t, "default", image.Source, "pin is no override")
+		}
+	}
+}
@@ -26,7 +26,7 @@ func (s *Service) infoService() (*InfoData, error) {
 
 	ctx, client, err := grpc.NewClient(ctx, s.config.Hostname, api.NewInfoServiceClient)
 	if err != nil {
-		return nil, &InfoServiceError{Message: err.Error(), Reason: ""}
+		return nil, &InfoServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 	defer func() {
 		_ = client.Close()
@@ -34,7 +34,7 @@ func (s *Service) infoService() (*InfoData, error) {
 
 	info, err := client.Handler().GetServiceInfo(ctx, &api.GetServiceInfoRequest{})
 	if err != nil {
-		return nil, &InfoServiceError{Message: err.Error(), Reason: ""}
+		return nil, &InfoServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	return &InfoData{
@@ -0,0 +1,85 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+type LogsOptions struct {
+	Components []string
+	Follow     bool
+	Since      string
+	Tail       string
+}
+
+// A duration like 30m or a timestamp like 2026-01-02T13:23:37Z.
+var logsSincePattern = regexp.MustCompile(`^[0-9A-Za-z.:+-]+$`)
+
+func (s *Service) __logsCommand(opts LogsOptions) (string, error) {
+	compose := "sudo docker compose --file " + path.Join(s.libDir(), "docker-compose.yaml")
+
+	out, err := s.target.RunForce(s.ctx, compose+" config --services")
+	if err != nil {
+		return "", &LogsServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+	services := strings.Fields(string(out))
+	for _, component := range opts.Components {
+		if !slices.Contains(services, component) {
+			return "", &LogsServiceError{
+				Message: "unknown component " + component,
+				Reason:  "use one of " + strings.Join(services, ", "),
+			}
+		}
+	}
+
+	cmd := compose + " logs --no-color"
+	if opts.Follow {
+		cmd += " --follow"
+	}
+	if opts.Since != "" {
+		if !logsSincePattern.MatchString(opts.Since) {
+			return "", &LogsServiceError{Message: "invalid since " + opts.Since, Reason: "use a duration or a timestamp"}
+		}
+		cmd += " --since " + opts.Since
+	}
+	if opts.Tail != "" && opts.Tail != "all" {
+		if _, err := strconv.ParseUint(opts.Tail, 10, 32); err != nil {
+			return "", &LogsServiceError{Message: "invalid tail " + opts.Tail, Reason: "use a number of lines or all"}
+		}
+		cmd += " --tail " + opts.Tail
+	}
+	if len(opts.Components) > 0 {
+		cmd += " " + strings.Join(opts.Components, " ")
+	}
+
+	return cmd + " 2>&1", nil
+}
+
+func (s *Service) logsService(opts LogsOptions, w io.Writer) error {
+	streamer, ok := s.target.(target.Streamer)
+	if !ok {
+		return &LogsServiceError{Message: "target does not support streaming", Reason: ""}
+	}
+
+	cmd, err := s.__logsCommand(opts)
+	if err != nil {
+		return err
+	}
+
+	if err := streamer.Stream(s.ctx, cmd, w); err != nil && s.ctx.Err() == nil {
+		return &LogsServiceError{Message: fmt.Sprintf("failed to stream logs: %s", err), Reason: ""}
+	}
+
+	return nil
+}
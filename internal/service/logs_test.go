@@ -0,0 +1,51 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func Test_Logs(t *testing.T) {
+	bin := t.TempDir()
+	docker := `#!/bin/sh
+case "$*" in
+*"config --services"*) printf 'finch\nhc-finch\nloki\n' ;;
+*logs*) echo "args: $*"; echo "loki  | level=info msg=ready" ;;
+esac
+`
+	err := os.WriteFile(bin+"/docker", []byte(docker), 0755)
+	assert.NoError(t, err, "write fake docker")
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	var out strings.Builder
+	err = s.Logs(LogsOptions{Components: []string{"loki"}, Follow: true, Since: "30m", Tail: "10"}, &out)
+	assert.NoError(t, err, "stream logs")
+	assert.Contains(t, out.String(), "logs --no-color --follow --since 30m --tail 10 loki\n", "logs command")
+	assert.Contains(t, out.String(), "loki  | level=info msg=ready\n", "prefixed log line")
+
+	err = s.Logs(LogsOptions{Components: []string{"prometheus"}}, &out)
+	assert.ErrorContains(t, err, "unknown component prometheus use one of finch, hc-finch, loki", "unknown component")
+
+	err = s.Logs(LogsOptions{Tail: "-1"}, &out)
+	assert.ErrorContains(t, err, "invalid tail -1", "invalid tail")
+
+	err = s.Logs(LogsOptions{Since: "1h; reboot"}, &out)
+	assert.ErrorContains(t, err, "invalid since", "invalid since")
+}
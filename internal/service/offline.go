@@ -0,0 +1,117 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+// __offlinePlatform returns the image platform of the target.
+func (s *Service) __offlinePlatform() (string, error) {
+	out, err := s.target.RunForce(s.ctx, "uname -m")
+	if err != nil {
+		return "", &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	switch machine := strings.TrimSpace(string(out)); machine {
+	case "x86_64":
+		return "linux/amd64", nil
+	case "aarch64":
+		return "linux/arm64", nil
+	default:
+		return "", &DeployServiceError{Message: "unsupported target architecture", Reason: machine}
+	}
+}
+
+// __offlineSaveImages pulls the images for platform on this machine and saves
+// them into file.
+func (s *Service) __offlineSaveImages(file, platform string, images []string) error {
+	var cmds [][]string
+	for _, image := range images {
+		cmds = append(cmds, []string{"docker", "pull", "--quiet", "--platform", platform, image})
+	}
+	cmds = append(cmds, append([]string{"docker", "save", "--output", file}, images...))
+
+	for _, cmd := range cmds {
+		s.__helperPrintProgress(fmt.Sprintf("Running '%s'", strings.Join(cmd, " ")))
+		if s.dryRun {
+			continue
+		}
+
+		if out, err := exec.CommandContext(s.ctx, cmd[0], cmd[1:]...).CombinedOutput(); err != nil {
+			return &DeployServiceError{Message: "failed to export images on this machine", Reason: strings.TrimSpace(string(out))}
+		}
+	}
+
+	return nil
+}
+
+// __offlineLoadImages exports the images of the stack on this machine and
+// loads them on the target, so it does not pull from any registry.
+func (s *Service) __offlineLoadImages() error {
+	if !s.offline {
+		return nil
+	}
+
+	platform, err := s.__offlinePlatform()
+	if err != nil {
+		return err
+	}
+
+	images := slices.Sorted(maps.Values(s.__images()))
+	images = slices.Compact(images)
+
+	f, err := os.CreateTemp("", "finch-images-*.tar")
+	if err != nil {
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+	_ = f.Close()
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	if err := s.__offlineSaveImages(f.Name(), platform, images); err != nil {
+		return err
+	}
+
+	raw, err := s.target.Run(s.ctx, "mktemp -p /tmp -d finch-XXXXXX")
+	if err != nil {
+		return &DeployServiceError{Message: err.Error(), Reason: string(raw), Err: err}
+	}
+	dir := strings.TrimSpace(string(raw))
+	if s.dryRun {
+		dir = target.DryRunTmpDir
+	}
+
+	dest := path.Join(dir, "images.tar")
+	if out, err := s.target.Copy(s.ctx, f.Name(), dest, "400", "0:0"); err != nil {
+		_, _ = s.target.Run(s.ctx, "sudo rm -rf "+dir)
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	load := fmt.Sprintf("sudo docker load --input %s; rc=$?; sudo rm -rf %s; exit $rc", dest, dir)
+	if out, err := s.target.Run(s.ctx, load); err != nil {
+		return &DeployServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	return nil
+}
+
+// __dockerRequireInstalled fails the offline deploy, Docker cannot be
+// installed without internet access.
+func (s *Service) __dockerRequireInstalled() error {
+	return &DeployServiceError{
+		Message: "Docker is not installed on the target",
+		Reason:  "install Docker and Docker Compose before an offline deploy",
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func Test_OfflineLoadImages(t *testing.T) {
+	bin := t.TempDir()
+	calls := bin + "/calls"
+	docker := "#!/bin/sh\necho \"$*\" >> " + calls + "\n" +
+		"case \"$1\" in save) echo images > \"$3\";; load) cat \"$3\" >> " + calls + ";; esac\n"
+	err := os.WriteFile(bin+"/docker", []byte(docker), 0755)
+	assert.NoError(t, err, "write fake docker")
+	uname := "#!/bin/sh\necho x86_64\n"
+	err = os.WriteFile(bin+"/uname", []byte(uname), 0755)
+	assert.NoError(t, err, "write fake uname")
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+		Offline:    true,
+		Config: &ServiceConfig{Images: map[string]string{
+			"grafana": "grafana/grafana:12.4.0",
+		}},
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.__offlineLoadImages()
+	assert.NoError(t, err, "load images")
+
+	content, err := os.ReadFile(calls)
+	assert.NoError(t, err, "read docker calls")
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, len(DefaultImages)+3, "pulls, save, load and loaded archive")
+	assert.Contains(t, lines, "pull --quiet --platform linux/amd64 grafana/grafana:12.4.0", "overridden image pulled")
+	assert.Regexp(t, "^save --output .+ curlimages/curl:8.21.0 ", lines[len(lines)-3], "images saved")
+	assert.Regexp(t, "^load --input /tmp/finch-.+/images.tar$", lines[len(lines)-2], "images loaded")
+	assert.Equal(t, "images", lines[len(lines)-1], "saved archive loaded")
+}
+
+func Test_OfflineUpdateRejectsPinnedStack(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	manifest := `{ "version": 1, "config": { "hostname": "localhost",
+		"pins": { "` + DefaultImages["loki"] + `": "` + DefaultImages["loki"] + `@sha256:9e4f" } } }`
+	err := os.WriteFile(os.Getenv(ServiceLibEnv)+"/"+stackManifestFile, []byte(manifest), 0600)
+	assert.NoError(t, err, "write stack manifest")
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		DryRun:     true,
+		Offline:    true,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.updateService()
+	assert.ErrorContains(t, err, "--offline cannot be used with a stack pinned by digest", "pinned stack")
+}
+
+func Test_OfflineDeployRequiresDocker(t *testing.T) {
+	bin := t.TempDir()
+	err := os.WriteFile(bin+"/docker", []byte("#!/bin/sh\nexit 127\n"), 0755)
+	assert.NoError(t, err, "write missing docker")
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		Offline:    true,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.dockerService()
+	assert.ErrorContains(t, err, "Docker is not installed on the target", "no install offline")
+}
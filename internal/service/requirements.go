@@ -5,8 +5,12 @@ Licensed under the MIT license, see LICENSE in the project root for details.
 package service
 
 func (s *Service) __requirementsHasSudo() error {
-	if _, err := s.target.Run(s.ctx, "command -v sudo"); err != nil {
-		return &DeployServiceError{Message: "sudo is not installed", Reason: err.Error()}
+	tool := s.escalation.Tool()
+	if tool == "" {
+		return nil
+	}
+	if _, err := s.target.Run(s.ctx, "command -v "+tool); err != nil {
+		return &DeployServiceError{Message: tool + " is not installed", Reason: err.Error()}
 	}
 	return nil
 }
@@ -19,8 +23,12 @@ func (s *Service) __requirementsHasCurl() error {
 }
 
 func (s *Service) __requirementsHasSudoPermission() error {
-	if _, err := s.target.Run(s.ctx, "sudo -n true"); err != nil {
-		return &DeployServiceError{Message: "user has no sudo permission", Reason: err.Error()}
+	if _, err := s.target.Run(s.ctx, s.escalation.PermissionCheck()); err != nil {
+		tool := s.escalation.Tool()
+		if tool == "" {
+			return &DeployServiceError{Message: "user is not root", Reason: err.Error()}
+		}
+		return &DeployServiceError{Message: "user has no " + tool + " permission", Reason: err.Error()}
 	}
 	return nil
 }
@@ -33,20 +41,25 @@ func (s *Service) __requirementsGitHubConnection() error {
 }
 
 func (s *Service) requirementsService() error {
-	if err := s.__requirementsHasSudo(); err != nil {
+	if err := s.tracker.Step("requirements.has-sudo", s.__requirementsHasSudo); err != nil {
 		return err
 	}
 
-	if err := s.__requirementsHasCurl(); err != nil {
-		return err
+	// Offline neither Docker is downloaded nor GitHub is reachable.
+	if !s.offline {
+		if err := s.tracker.Step("requirements.has-curl", s.__requirementsHasCurl); err != nil {
+			return err
+		}
 	}
 
-	if err := s.__requirementsHasSudoPermission(); err != nil {
+	if err := s.tracker.Step("requirements.has-sudo-permission", s.__requirementsHasSudoPermission); err != nil {
 		return err
 	}
 
-	if err := s.__requirementsGitHubConnection(); err != nil {
-		return err
+	if !s.offline {
+		if err := s.tracker.Step("requirements.github-connection", s.__requirementsGitHubConnection); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,214 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+type RestoreOptions struct {
+	From string
+}
+
+// __restoreReadManifest reads the manifest of the local archive file. The
+// local tar detects the compression itself.
+func (s *Service) __restoreReadManifest(file string) (*BackupManifest, error) {
+	out, err := exec.CommandContext(s.ctx, "tar", "-xOf", file, backupManifestFile).Output()
+	if err != nil {
+		return nil, &RestoreServiceError{Message: "failed to read backup manifest", Reason: err.Error()}
+	}
+
+	var manifest BackupManifest
+	if err := json.Unmarshal(out, &manifest); err != nil {
+		return nil, &RestoreServiceError{Message: "failed to read backup manifest", Reason: err.Error()}
+	}
+	if manifest.Version != 1 {
+		return nil, &RestoreServiceError{Message: fmt.Sprintf("unsupported backup manifest version %d", manifest.Version), Reason: ""}
+	}
+	if manifest.Hostname == "" {
+		return nil, &RestoreServiceError{Message: "backup manifest has no hostname", Reason: ""}
+	}
+
+	return &manifest, nil
+}
+
+// __restoreIsDeployed fails if the service is deployed on the target or the
+// target cannot tell.
+func (s *Service) __restoreIsDeployed() error {
+	cfgPath := path.Join(s.libDir(), "finch.json")
+	out, err := s.target.RunForce(s.ctx, "sudo test -e "+cfgPath)
+	if err == nil {
+		return &RestoreServiceError{Message: "service already deployed on target", Reason: ""}
+	}
+
+	var exitErr *target.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Status != 1 {
+		return &RestoreServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	return nil
+}
+
+// __restoreVerifyChecksums verifies the files unpacked into data against the
+// checksums of the manifest, the list of checksums is uploaded to dir.
+func (s *Service) __restoreVerifyChecksums(dir, data string, checksums map[string]string) error {
+	if len(checksums) == 0 {
+		return nil
+	}
+
+	var sums strings.Builder
+	for _, file := range slices.Sorted(maps.Keys(checksums)) {
+		fmt.Fprintf(&sums, "%s  %s\n", checksums[file], file)
+	}
+
+	f, err := os.CreateTemp("", "finch-checksums-*")
+	if err != nil {
+		return &RestoreServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+	if _, err := f.WriteString(sums.String()); err != nil {
+		_ = f.Close()
+		return &RestoreServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+	if err := f.Close(); err != nil {
+		return &RestoreServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	sumsPath := path.Join(dir, "SHA256SUMS")
+	if out, err := s.target.Copy(s.ctx, f.Name(), sumsPath, "400", "0:0"); err != nil {
+		return &RestoreServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	if out, err := s.target.Run(s.ctx, fmt.Sprintf("cd %s && sudo sha256sum --quiet -c %s", data, sumsPath)); err != nil {
+		return &RestoreServiceError{Message: "checksum verification failed", Reason: string(out)}
+	}
+
+	return nil
+}
+
+func (s *Service) __restoreSetDirHierarchyPermission() error {
+	var dirs []string
+	owned := map[string][]string{}
+	for _, dir := range slices.Sorted(maps.Keys(dirHierarchy)) {
+		p := path.Join(s.libDir(), dir)
+		dirs = append(dirs, p)
+		owned[dirHierarchy[dir]] = append(owned[dirHierarchy[dir]], p)
+	}
+
+	cmds := []string{"sudo mkdir -p " + strings.Join(dirs, " ")}
+	for _, owner := range slices.Sorted(maps.Keys(owned)) {
+		cmds = append(cmds, fmt.Sprintf("sudo chown %s %s", owner, strings.Join(owned[owner], " ")))
+	}
+
+	if out, err := s.target.Run(s.ctx, strings.Join(cmds, " && ")); err != nil {
+		return &RestoreServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	return nil
+}
+
+func (s *Service) restoreService(opts RestoreOptions) error {
+	compression, err := backupCompression(opts.From)
+	if err != nil {
+		return &RestoreServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	manifest, err := s.__restoreReadManifest(opts.From)
+	if err != nil {
+		return err
+	}
+	s.config.Hostname = manifest.Hostname
+
+	if err := s.tracker.Step("restore.is-deployed", s.__restoreIsDeployed); err != nil {
+		return err
+	}
+
+	if compression == "--zstd" {
+		if out, err := s.target.Run(s.ctx, "command -v zstd"); err != nil {
+			return &RestoreServiceError{Message: "zstd is not installed", Reason: string(out)}
+		}
+	}
+
+	raw, err := s.target.Run(s.ctx, "mktemp -p /tmp -d finch-XXXXXX")
+	if err != nil {
+		return &RestoreServiceError{Message: err.Error(), Reason: string(raw), Err: err}
+	}
+	dir := strings.TrimSpace(string(raw))
+	if s.dryRun {
+		dir = target.DryRunTmpDir
+	}
+	defer func() {
+		_, _ = s.target.Run(s.ctx, "sudo rm -rf "+dir)
+	}()
+
+	// The archive is unpacked and verified aside, a corrupt archive leaves
+	// nothing behind and the restore can be retried.
+	archive := path.Join(dir, path.Base(opts.From))
+	data := path.Join(dir, "data")
+	if err := s.tracker.Step("restore.upload-archive", func() error {
+		if out, err := s.target.Copy(s.ctx, opts.From, archive, "400", "0:0"); err != nil {
+			return &RestoreServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("restore.unpack-archive", func() error {
+		cmd := fmt.Sprintf("sudo mkdir -p %s && sudo tar %s --exclude %s -xpf %s -C %s", data, compression, backupManifestFile, archive, data)
+		if out, err := s.target.Run(s.ctx, strings.Join(strings.Fields(cmd), " ")); err != nil {
+			return &RestoreServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("restore.verify-checksums", func() error {
+		return s.__restoreVerifyChecksums(dir, data, manifest.Checksums)
+	}); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("restore.install-files", func() error {
+		cmd := fmt.Sprintf("sudo mkdir -p %[1]s && sudo cp -a %[2]s/. %[1]s", s.libDir(), data)
+		if out, err := s.target.Run(s.ctx, cmd); err != nil {
+			return &RestoreServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("restore.set-dir-hierarchy-permission", s.__restoreSetDirHierarchyPermission); err != nil {
+		return err
+	}
+
+	if err := s.tracker.Step("restore.generate-mtls-certificates", s.__deployGenerateMTLSCertificates); err != nil {
+		return convertError(err, &RestoreServiceError{})
+	}
+
+	if err := s.tracker.Step("restore.compose-up", s.__deployComposeUp); err != nil {
+		return convertError(err, &RestoreServiceError{})
+	}
+
+	if err := s.tracker.Step("restore.compose-ready", s.__deployComposeReady); err != nil {
+		return convertError(err, &RestoreServiceError{})
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/goccy/go-yaml"
+)
+
+const defaultRetention = "72h"
+
+// Loki, Mimir and Pyroscope take durations like 30d or 1w2d, a sequence of
+// numbers with unit.
+var retentionPattern = regexp.MustCompile(`^([0-9]+(ms|s|m|h|d|w|y))+$`)
+
+type Retention struct {
+	Logs     string `json:"logs"`
+	Metrics  string `json:"metrics"`
+	Profiles string `json:"profiles"`
+}
+
+// ValidateRetention returns an error if period is not a retention period.
+func ValidateRetention(period string) error {
+	if !retentionPattern.MatchString(period) {
+		return fmt.Errorf("invalid retention period %q, use a duration like 30d or 720h", period)
+	}
+
+	return nil
+}
+
+// __retention returns the configured retention, unset periods default to
+// 72h.
+func (s *Service) __retention() Retention {
+	retention := s.config.Retention
+	for _, p := range []*string{&retention.Logs, &retention.Metrics, &retention.Profiles} {
+		if *p == "" {
+			*p = defaultRetention
+		}
+	}
+
+	return retention
+}
+
+// __updateReadRetention reads the retention of a stack deployed without a
+// stack manifest from the Loki, Mimir and Pyroscope configs, so an update
+// keeps it.
+func (s *Service) __updateReadRetention() error {
+	if s.manifest != nil {
+		return nil
+	}
+
+	configs := []struct {
+		file   string
+		period *string
+	}{
+		{"loki/etc/loki.yaml", &s.config.Retention.Logs},
+		{"mimir/etc/mimir.yaml", &s.config.Retention.Metrics},
+		{"pyroscope/etc/pyroscope.yaml", &s.config.Retention.Profiles},
+	}
+
+	for _, c := range configs {
+		out, err := s.target.RunForce(s.ctx, "sudo cat "+path.Join(s.libDir(), c.file))
+		if err != nil {
+			return &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+
+		var cfg struct {
+			LimitsConfig struct {
+				RetentionPeriod string `yaml:"retention_period"`
+			} `yaml:"limits_config"`
+			Limits struct {
+				CompactorBlocksRetentionPeriod string `yaml:"compactor_blocks_retention_period"`
+			} `yaml:"limits"`
+		}
+		if err := yaml.Unmarshal(out, &cfg); err != nil {
+			return &UpdateServiceError{Message: "failed to read retention from " + c.file, Reason: err.Error()}
+		}
+
+		*c.period = cfg.LimitsConfig.RetentionPeriod
+		if *c.period == "" {
+			*c.period = cfg.Limits.CompactorBlocksRetentionPeriod
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateRetention(t *testing.T) {
+	for _, period := range []string{"72h", "30d", "1w2d", "1y"} {
+		assert.NoError(t, ValidateRetention(period), period)
+	}
+
+	for _, period := range []string{"", "30", "30 days", "-1d", "d30"} {
+		assert.Error(t, ValidateRetention(period), period)
+	}
+}
+
+func Test_RetentionRendersIntoConfigs(t *testing.T) {
+	s := &Service{config: &ServiceConfig{Retention: Retention{Logs: "30d"}}}
+
+	loki, err := s.__helperRenderTemplate("loki.yaml", s.__retention())
+	assert.NoError(t, err, "render loki.yaml")
+	assert.Contains(t, string(loki), "retention_period: 30d", "logs retention")
+
+	mimir, err := s.__helperRenderTemplate("mimir.yaml", s.__retention())
+	assert.NoError(t, err, "render mimir.yaml")
+	assert.Contains(t, string(mimir), "compactor_blocks_retention_period: 72h", "default metrics retention")
+
+	pyroscope, err := s.__helperRenderTemplate("pyroscope.yaml", s.__retention())
+	assert.NoError(t, err, "render pyroscope.yaml")
+	assert.Contains(t, string(pyroscope), "max_query_lookback: 72h", "default profiles retention")
+}
@@ -16,7 +16,7 @@ func (s *Service) rotateCertificate() error {
 	}
 
 	if _, err := config.LookupStack(s.config.Hostname); err != nil {
-		return &RotateServiceCertificateError{Message: err.Error(), Reason: ""}
+		return &RotateServiceCertificateError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	if err := s.__deployGenerateMTLSCertificates(); err != nil {
@@ -26,7 +26,7 @@ func (s *Service) rotateCertificate() error {
 	obsoleteCACertPath := path.Join(s.libDir(), "traefik/etc/certs.d/ca.pem")
 	out, err := s.target.Run(s.ctx, "rm -f "+obsoleteCACertPath)
 	if err != nil {
-		return &RotateServiceCertificateError{Message: err.Error(), Reason: string(out)}
+		return &RotateServiceCertificateError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -19,27 +19,27 @@ func (s *Service) rotateServiceSecret() error {
 	}
 
 	if _, err := config.LookupStack(s.config.Hostname); err != nil {
-		return &RotateServiceSecretError{Message: err.Error(), Reason: ""}
+		return &RotateServiceSecretError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	cfgPath := path.Join(s.libDir(), "finch.json")
-	out, err := s.target.Run(s.ctx, "sudo cat "+cfgPath)
+	out, err := s.__helperFetchFile(cfgPath)
 	if err != nil {
-		return &RotateServiceSecretError{Message: err.Error(), Reason: string(out)}
+		return convertError(err, &RotateServiceSecretError{})
 	}
-	if s.dryRun {
+	if s.dryRun && s.planOut == "" {
 		out = []byte(`{}`)
 	}
 
 	cfg := FinchConfig{}
 	err = json.Unmarshal(out, &cfg)
 	if err != nil {
-		return &RotateServiceSecretError{Message: err.Error(), Reason: string(out)}
+		return &RotateServiceSecretError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	key := make([]byte, 32)
 	if _, err := rand.Read(key); err != nil {
-		return &RotateServiceSecretError{Message: err.Error(), Reason: ""}
+		return &RotateServiceSecretError{Message: err.Error(), Reason: "", Err: err}
 	}
 	secret := base64.StdEncoding.EncodeToString(key)
 	cfg.Secret = secret
@@ -50,7 +50,7 @@ func (s *Service) rotateServiceSecret() error {
 
 	out, err = s.target.Run(s.ctx, "sudo docker compose --file "+path.Join(s.libDir(), "docker-compose.yaml")+" restart finch")
 	if err != nil {
-		return &RotateServiceSecretError{Message: err.Error(), Reason: string(out)}
+		return &RotateServiceSecretError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	return nil
@@ -0,0 +1,222 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A certificate expiring within this period is installed with a warning.
+const tlsExpiryWarning = 30 * 24 * time.Hour
+
+type RotateTLSOptions struct {
+	CertFilePath string
+	KeyFilePath  string
+}
+
+// validateTLSPair returns the leaf certificate of the pair if the key matches
+// the certificate and the certificate is valid for hostname.
+func validateTLSPair(certFile, keyFile, hostname string) (*x509.Certificate, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate and key pair: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	if err := leaf.VerifyHostname(hostname); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.After(leaf.NotAfter) {
+		return nil, fmt.Errorf("certificate expired on %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+	if now.Before(leaf.NotBefore) {
+		return nil, fmt.Errorf("certificate not valid before %s", leaf.NotBefore.Format(time.RFC3339))
+	}
+
+	return leaf, nil
+}
+
+// __rotateTLSInstall uploads the pair next to the deployed one, moves the
+// deployed pair aside and renames the new one into place, Traefik never reads
+// a partially written file.
+func (s *Service) __rotateTLSInstall(opts RotateTLSOptions) error {
+	dir := path.Join(s.libDir(), "traefik/etc/certs.d")
+	files := map[string]string{
+		"cert": opts.CertFilePath,
+		"key":  opts.KeyFilePath,
+	}
+	for k, v := range files {
+		content, err := os.ReadFile(v)
+		if err != nil {
+			return &RotateServiceTLSError{Message: err.Error(), Reason: "", Err: err}
+		}
+		if err := s.__helperCopyContent(path.Join(dir, k+".pem.new"), "400", "0:0", content); err != nil {
+			return convertError(err, &RotateServiceTLSError{})
+		}
+	}
+
+	backup := fmt.Sprintf("sudo rm -f %[1]s/key.pem.old %[1]s/cert.pem.old && "+
+		"if sudo test -e %[1]s/key.pem; then sudo cp -p %[1]s/key.pem %[1]s/key.pem.old; fi && "+
+		"if sudo test -e %[1]s/cert.pem; then sudo cp -p %[1]s/cert.pem %[1]s/cert.pem.old; fi", dir)
+	if out, err := s.target.Run(s.ctx, backup); err != nil {
+		return &RotateServiceTLSError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	rename := fmt.Sprintf("sudo mv -f %[1]s/key.pem.new %[1]s/key.pem && sudo mv -f %[1]s/cert.pem.new %[1]s/cert.pem", dir)
+	if out, err := s.target.Run(s.ctx, rename); err != nil {
+		return &RotateServiceTLSError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	return s.__rotateTLSRestartTraefik()
+}
+
+func (s *Service) __rotateTLSRestartTraefik() error {
+	compose := "sudo docker compose --file " + path.Join(s.libDir(), "docker-compose.yaml")
+	if out, err := s.target.Run(s.ctx, compose+" restart traefik"); err != nil {
+		return &RotateServiceTLSError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	return nil
+}
+
+// __rotateTLSRestore moves the previous pair back into place and restarts
+// Traefik.
+func (s *Service) __rotateTLSRestore() error {
+	dir := path.Join(s.libDir(), "traefik/etc/certs.d")
+	restore := fmt.Sprintf("if sudo test -e %[1]s/key.pem.old; then sudo mv -f %[1]s/key.pem.old %[1]s/key.pem; fi && "+
+		"if sudo test -e %[1]s/cert.pem.old; then sudo mv -f %[1]s/cert.pem.old %[1]s/cert.pem; fi", dir)
+	if out, err := s.target.Run(s.ctx, restore); err != nil {
+		return &RotateServiceTLSError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	return s.__rotateTLSRestartTraefik()
+}
+
+// __rotateTLSRestored reports the failed rotation err, the previous pair was
+// restored unless restoreErr is set.
+func (s *Service) __rotateTLSRestored(err, restoreErr error) error {
+	rerr, ok := convertError(err, &RotateServiceTLSError{}).(*RotateServiceTLSError)
+	if !ok {
+		rerr = &RotateServiceTLSError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	if restoreErr != nil {
+		rerr.Message += fmt.Sprintf(", restore failed, previous certificate kept in %s: %s",
+			path.Join(s.libDir(), "traefik/etc/certs.d/cert.pem.old"), restoreErr.Error())
+		return rerr
+	}
+	rerr.Message += ", restored the previous certificate"
+
+	return rerr
+}
+
+// __rotateTLSVerify waits for Traefik to serve a certificate with the public
+// key of leaf.
+func (s *Service) __rotateTLSVerify(leaf *x509.Certificate) error {
+	sum := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	pin := base64.StdEncoding.EncodeToString(sum[:])
+	cmd := fmt.Sprintf("curl --silent --output /dev/null --insecure --pinnedpubkey sha256//%s --resolve %s:443:127.0.0.1 https://%s/",
+		pin, s.config.Hostname, s.config.Hostname)
+
+	var out []byte
+	var err error
+	deadline := time.Now().Add(readinessMaxWait)
+	for time.Now().Before(deadline) {
+		if out, err = s.target.Run(s.ctx, cmd); err == nil {
+			return nil
+		}
+		time.Sleep(readinessInterval)
+	}
+
+	return &RotateServiceTLSError{Message: "Traefik does not serve the new certificate", Reason: strings.TrimSpace(string(out) + " " + err.Error())}
+}
+
+// __rotateTLSWriteManifest records the custom TLS pair in the stack manifest,
+// a later update keeps it. A stack deployed without a manifest gets one
+// derived from the deployed files.
+func (s *Service) __rotateTLSWriteManifest() error {
+	now := time.Now().Format(time.RFC3339)
+	manifest := StackManifest{Version: stackManifestVersion, CreatedAt: now}
+	if s.manifest != nil {
+		manifest = *s.manifest
+	} else {
+		fmt.Fprintf(os.Stderr, "Warning: no stack manifest on %s, writing one derived from the deployed files\n",
+			s.config.Hostname)
+		if err := s.__updateReadRetention(); err != nil {
+			return convertError(err, &RotateServiceTLSError{})
+		}
+	}
+	manifest.UpdatedAt = now
+	manifest.Config = *s.config
+	manifest.Config.CustomTLS.Enabled = true
+	manifest.Config.CustomTLS.CertFilePath = ""
+	manifest.Config.CustomTLS.KeyFilePath = ""
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return &RotateServiceTLSError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	err = s.__helperCopyContent(path.Join(s.libDir(), stackManifestFile), "400", "0:0", append(data, '\n'))
+	return convertError(err, &RotateServiceTLSError{})
+}
+
+func (s *Service) rotateTLS(opts RotateTLSOptions) error {
+	if s.planOut != "" {
+		return &RotateServiceTLSError{Message: "rotation cannot be planned", Reason: "the new certificate is verified and rolled back while running"}
+	}
+
+	var err error
+	for _, file := range []*string{&opts.CertFilePath, &opts.KeyFilePath} {
+		if *file, err = filepath.Abs(*file); err != nil {
+			return &RotateServiceTLSError{Message: err.Error(), Reason: "", Err: err}
+		}
+	}
+
+	if err := s.__updateSetTargetConfiguration(); err != nil {
+		return convertError(err, &RotateServiceTLSError{})
+	}
+	if s.config.LetsEncrypt.Enabled {
+		return &RotateServiceTLSError{Message: "service uses Let's Encrypt certificates", Reason: ""}
+	}
+
+	leaf, err := validateTLSPair(opts.CertFilePath, opts.KeyFilePath, s.config.Hostname)
+	if err != nil {
+		return &RotateServiceTLSError{Message: err.Error(), Reason: "", Err: err}
+	}
+	if time.Until(leaf.NotAfter) < tlsExpiryWarning {
+		fmt.Fprintf(os.Stderr, "Warning: certificate for %s expires on %s\n",
+			s.config.Hostname, leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	if err := s.__rotateTLSInstall(opts); err != nil {
+		return err
+	}
+	if err := s.__rotateTLSVerify(leaf); err != nil {
+		return s.__rotateTLSRestored(err, s.__rotateTLSRestore())
+	}
+
+	dir := path.Join(s.libDir(), "traefik/etc/certs.d")
+	if out, err := s.target.Run(s.ctx, fmt.Sprintf("sudo rm -f %[1]s/key.pem.old %[1]s/cert.pem.old", dir)); err != nil {
+		return &RotateServiceTLSError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	return s.__rotateTLSWriteManifest()
+}
@@ -0,0 +1,248 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func writeTLSPair(t *testing.T, hostname string, notAfter time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err, "generate key")
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{hostname},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.NoError(t, err, "create certificate")
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err, "marshal key")
+
+	dir := t.TempDir()
+	certFile, keyFile := dir+"/cert.pem", dir+"/key.pem"
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600)
+	assert.NoError(t, err, "write certificate")
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	assert.NoError(t, err, "write key")
+
+	return certFile, keyFile
+}
+
+func Test_ValidateTLSPair(t *testing.T) {
+	certFile, keyFile := writeTLSPair(t, "finch.example.com", time.Now().Add(90*24*time.Hour))
+
+	leaf, err := validateTLSPair(certFile, keyFile, "finch.example.com")
+	assert.NoError(t, err, "valid pair")
+	assert.Equal(t, []string{"finch.example.com"}, leaf.DNSNames, "leaf certificate")
+
+	_, err = validateTLSPair(certFile, keyFile, "grafana.example.com")
+	assert.ErrorContains(t, err, "not grafana.example.com", "hostname not covered")
+
+	_, otherKeyFile := writeTLSPair(t, "finch.example.com", time.Now().Add(time.Hour))
+	_, err = validateTLSPair(certFile, otherKeyFile, "finch.example.com")
+	assert.ErrorContains(t, err, "invalid certificate and key pair", "key does not match")
+
+	expiredCertFile, expiredKeyFile := writeTLSPair(t, "finch.example.com", time.Now().Add(-time.Minute))
+	_, err = validateTLSPair(expiredCertFile, expiredKeyFile, "finch.example.com")
+	assert.ErrorContains(t, err, "certificate expired", "expired certificate")
+}
+
+func Test_DeployCopyCustomTLSValidatesPair(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	certFile, _ := writeTLSPair(t, "localhost", time.Now().Add(90*24*time.Hour))
+	_, otherKeyFile := writeTLSPair(t, "localhost", time.Now().Add(90*24*time.Hour))
+
+	config := &ServiceConfig{Hostname: "localhost"}
+	config.CustomTLS.Enabled = true
+	config.CustomTLS.CertFilePath = certFile
+	config.CustomTLS.KeyFilePath = otherKeyFile
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		DryRun:     true,
+		CmdTimeout: 300 * time.Second,
+		Config:     config,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.__deployCopyTraefikHttpTlsConfig()
+	assert.ErrorContains(t, err, "invalid custom TLS files", "key does not match")
+
+	config.CustomTLS.CertFilePath = ""
+	config.CustomTLS.KeyFilePath = ""
+	err = s.__deployCopyTraefikHttpTlsConfig()
+	assert.NoError(t, err, "deployed pair kept")
+}
+
+func Test_RotateTLS(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	manifest := `{ "version": 1, "config": { "hostname": "localhost" } }`
+	err := os.WriteFile(os.Getenv(ServiceLibEnv)+"/"+stackManifestFile, []byte(manifest), 0600)
+	assert.NoError(t, err, "write stack manifest")
+
+	certFile, keyFile := writeTLSPair(t, "localhost", time.Now().Add(90*24*time.Hour))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatDocumentation,
+		DryRun:     true,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	record := capture(func() {
+		err = s.RotateTLS(RotateTLSOptions{CertFilePath: certFile, KeyFilePath: keyFile})
+	})
+	assert.NoError(t, err, "rotate TLS certificate")
+
+	assert.Regexp(t, "Copying from '.+' to '.+/traefik/etc/certs.d/cert.pem.new'", record, "certificate uploaded")
+	assert.Regexp(t, "Running '.+then sudo cp -p .+/cert.pem .+/cert.pem.old; fi'", record, "deployed pair moved aside")
+	assert.Regexp(t, "Running 'sudo mv -f .+/key.pem.new .+/key.pem && sudo mv -f .+/cert.pem.new .+/cert.pem'", record, "pair renamed into place")
+	assert.Regexp(t, "Running 'sudo docker compose --file .+ restart traefik'", record, "traefik restarted")
+	assert.Regexp(t, "Running 'curl .+--pinnedpubkey sha256//.+ --resolve localhost:443:127.0.0.1 https://localhost/'", record, "served certificate verified")
+	assert.Regexp(t, "Running 'sudo rm -f .+/key.pem.old .+/cert.pem.old'", record, "previous pair removed")
+	assert.Regexp(t, "Copying from '.+' to '.+/stack.json'", record, "new pair recorded in stack manifest")
+
+	otherCertFile, otherKeyFile := writeTLSPair(t, "finch.example.com", time.Now().Add(90*24*time.Hour))
+	err = s.RotateTLS(RotateTLSOptions{CertFilePath: otherCertFile, KeyFilePath: otherKeyFile})
+	assert.ErrorContains(t, err, "not localhost", "hostname not covered")
+}
+
+func Test_RotateTLSWritesMissingManifest(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	libDir := os.Getenv(ServiceLibEnv)
+	for file, content := range map[string]string{
+		"loki/etc/loki.yaml":           "limits_config:\n  retention_period: 30d\n",
+		"mimir/etc/mimir.yaml":         "limits:\n  compactor_blocks_retention_period: 90d\n",
+		"pyroscope/etc/pyroscope.yaml": "limits:\n  compactor_blocks_retention_period: 7d\n",
+	} {
+		err := os.MkdirAll(path.Dir(libDir+"/"+file), 0700)
+		assert.NoError(t, err, "create config dir")
+		err = os.WriteFile(libDir+"/"+file, []byte(content), 0600)
+		assert.NoError(t, err, "write retention config")
+	}
+	err := os.MkdirAll(libDir+"/traefik/etc/certs.d", 0700)
+	assert.NoError(t, err, "create certs dir")
+
+	bin := t.TempDir()
+	for _, name := range []string{"docker", "curl"} {
+		err := os.WriteFile(bin+"/"+name, []byte("#!/bin/sh\nexit 0\n"), 0755)
+		assert.NoError(t, err, "write fake "+name)
+	}
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	certFile, keyFile := writeTLSPair(t, "localhost", time.Now().Add(90*24*time.Hour))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.RotateTLS(RotateTLSOptions{CertFilePath: certFile, KeyFilePath: keyFile})
+	assert.NoError(t, err, "rotate TLS certificate")
+	assert.FileExists(t, libDir+"/traefik/etc/certs.d/cert.pem", "certificate installed")
+
+	s.config = &ServiceConfig{}
+	err = s.__updateSetTargetConfiguration()
+	assert.NoError(t, err, "read written stack manifest")
+	assert.True(t, s.config.CustomTLS.Enabled, "custom TLS recorded")
+	assert.Equal(t, "localhost", s.config.Hostname, "hostname recorded")
+	assert.Equal(t, Retention{Logs: "30d", Metrics: "90d", Profiles: "7d"}, s.config.Retention, "retention recorded")
+}
+
+func Test_RotateTLSRestoresPreviousPair(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	wait, interval := readinessMaxWait, readinessInterval
+	readinessMaxWait, readinessInterval = 50*time.Millisecond, 10*time.Millisecond
+	defer func() { readinessMaxWait, readinessInterval = wait, interval }()
+
+	bin := t.TempDir()
+	for name, script := range map[string]string{
+		"docker": "#!/bin/sh\nexit 0\n",
+		"curl":   "#!/bin/sh\ncase \"$*\" in *github.com*) exit 0;; esac\nexit 7\n",
+	} {
+		err := os.WriteFile(bin+"/"+name, []byte(script), 0755)
+		assert.NoError(t, err, "write fake "+name)
+	}
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	libDir := os.Getenv(ServiceLibEnv)
+	manifest := `{ "version": 1, "config": { "hostname": "localhost" } }`
+	err := os.WriteFile(libDir+"/"+stackManifestFile, []byte(manifest), 0600)
+	assert.NoError(t, err, "write stack manifest")
+
+	certsDir := libDir + "/traefik/etc/certs.d"
+	err = os.MkdirAll(certsDir, 0700)
+	assert.NoError(t, err, "create certs dir")
+	for _, file := range []string{"cert.pem", "key.pem"} {
+		err = os.WriteFile(certsDir+"/"+file, []byte("previous "+file), 0400)
+		assert.NoError(t, err, "write previous "+file)
+	}
+
+	certFile, keyFile := writeTLSPair(t, "localhost", time.Now().Add(90*24*time.Hour))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.RotateTLS(RotateTLSOptions{CertFilePath: certFile, KeyFilePath: keyFile})
+	assert.ErrorContains(t, err, "Traefik does not serve the new certificate", "verification failed")
+	assert.ErrorContains(t, err, "restored the previous certificate", "previous pair restored")
+
+	for _, file := range []string{"cert.pem", "key.pem"} {
+		content, err := os.ReadFile(certsDir + "/" + file)
+		assert.NoError(t, err, "read "+file)
+		assert.Equal(t, "previous "+file, string(content), "previous "+file)
+		assert.NoFileExists(t, certsDir+"/"+file+".old", "no backup left")
+	}
+}
+
+func Test_RotateTLSRefusesPlan(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	certFile, keyFile := writeTLSPair(t, "localhost", time.Now().Add(90*24*time.Hour))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		PlanOut:    t.TempDir() + "/plan.sh",
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.rotateTLS(RotateTLSOptions{CertFilePath: certFile, KeyFilePath: keyFile})
+	assert.ErrorContains(t, err, "rotation cannot be planned", "plan refused")
+}
@@ -6,6 +6,7 @@ package service
 
 import (
 	"context"
+	"io"
 	"os"
 	"time"
 
@@ -19,23 +20,34 @@ const (
 type Service struct {
 	ctx        context.Context
 	config     *ServiceConfig
+	manifest   *StackManifest
 	target     target.Target
+	host       string
+	bundle     *bundle
+	tracker    *target.Tracker
 	format     target.Format
 	dryRun     bool
+	planOut    string
+	pinDigests bool
+	offline    bool
 	cmdTimeout time.Duration
+	escalation target.Escalation
 }
 
 type ServiceConfig struct {
-	Hostname    string
+	Hostname    string `json:"hostname"`
 	LetsEncrypt struct {
-		Enabled bool
-		Email   string
-	}
+		Enabled bool   `json:"enabled"`
+		Email   string `json:"email"`
+	} `json:"letsencrypt"`
 	CustomTLS struct {
-		Enabled      bool
-		CertFilePath string
-		KeyFilePath  string
-	}
+		Enabled      bool   `json:"enabled"`
+		CertFilePath string `json:"cert_file_path"`
+		KeyFilePath  string `json:"key_file_path"`
+	} `json:"custom_tls"`
+	Retention Retention         `json:"retention"`
+	Images    map[string]string `json:"images,omitempty"`
+	Pins      map[string]string `json:"pins,omitempty"`
 }
 
 type FinchConfig struct {
@@ -52,14 +64,24 @@ type Options struct {
 	TargetURL  string
 	Format     target.Format
 	DryRun     bool
+	PlanOut    string
 	CmdTimeout time.Duration
+	Escalation target.Escalation
+	Retry      target.RetryOptions
+	SSH        target.SSHOptions
+	PinDigests bool
+	Offline    bool
 }
 
 func New(ctx context.Context, opts Options) (*Service, error) {
 	t, err := target.New(opts.TargetURL, target.Options{
 		Format:     opts.Format,
-		DryRun:     opts.DryRun,
+		DryRun:     opts.DryRun || opts.PlanOut != "",
+		PlanOut:    opts.PlanOut,
 		CmdTimeout: opts.CmdTimeout,
+		Escalation: opts.Escalation,
+		Retry:      opts.Retry,
+		SSH:        opts.SSH,
 	})
 	if err != nil {
 		return nil, err
@@ -74,9 +96,14 @@ func New(ctx context.Context, opts Options) (*Service, error) {
 		ctx:        ctx,
 		config:     config,
 		target:     t,
+		host:       opts.TargetURL,
 		format:     opts.Format,
-		dryRun:     opts.DryRun,
+		dryRun:     opts.DryRun || opts.PlanOut != "",
+		planOut:    opts.PlanOut,
+		pinDigests: opts.PinDigests,
+		offline:    opts.Offline,
 		cmdTimeout: opts.CmdTimeout,
+		escalation: opts.Escalation,
 	}, nil
 }
 
@@ -98,11 +125,13 @@ func (s *Service) Teardown() error {
 	return nil
 }
 
-func (s *Service) Deploy() error {
+func (s *Service) Deploy() (err error) {
+	s.tracker = target.NewTracker("service.deploy", s.host, s.format, os.Stdout)
 	defer func() {
 		if s.format == target.FormatProgress {
 			println()
 		}
+		s.tracker.Summary(err)
 	}()
 
 	if err := s.requirementsService(); err != nil {
@@ -120,11 +149,13 @@ func (s *Service) Deploy() error {
 	return nil
 }
 
-func (s *Service) Update() error {
+func (s *Service) Update() (err error) {
+	s.tracker = target.NewTracker("service.update", s.host, s.format, os.Stdout)
 	defer func() {
 		if s.format == target.FormatProgress {
 			println()
 		}
+		s.tracker.Summary(err)
 	}()
 
 	if err := s.requirementsService(); err != nil {
@@ -217,6 +248,24 @@ func (s *Service) RotateCertificate() error {
 	return nil
 }
 
+func (s *Service) RotateTLS(opts RotateTLSOptions) error {
+	defer func() {
+		if s.format == target.FormatProgress {
+			println()
+		}
+	}()
+
+	if err := s.requirementsService(); err != nil {
+		return convertError(err, &RotateServiceTLSError{})
+	}
+
+	if err := s.rotateTLS(opts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *Service) RotateSecret() error {
 	defer func() {
 		if s.format == target.FormatProgress {
@@ -235,6 +284,97 @@ func (s *Service) RotateSecret() error {
 	return nil
 }
 
+func (s *Service) Backup(opts BackupOptions) (err error) {
+	s.tracker = target.NewTracker("service.backup", s.host, s.format, os.Stdout)
+	defer func() {
+		if s.format == target.FormatProgress {
+			println()
+		}
+		s.tracker.Summary(err)
+	}()
+
+	// A backup downloads nothing, so neither curl nor GitHub is required.
+	if err := s.__requirementsHasSudo(); err != nil {
+		return convertError(err, &BackupServiceError{})
+	}
+	if err := s.__requirementsHasSudoPermission(); err != nil {
+		return convertError(err, &BackupServiceError{})
+	}
+
+	if err := s.backupService(opts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) Restore(opts RestoreOptions) (err error) {
+	s.tracker = target.NewTracker("service.restore", s.host, s.format, os.Stdout)
+	defer func() {
+		if s.format == target.FormatProgress {
+			println()
+		}
+		s.tracker.Summary(err)
+	}()
+
+	if err := s.requirementsService(); err != nil {
+		return convertError(err, &RestoreServiceError{})
+	}
+
+	if err := s.dockerService(); err != nil {
+		return convertError(err, &RestoreServiceError{})
+	}
+
+	if err := s.restoreService(opts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) Images() ([]ImageData, error) {
+	defer func() {
+		if s.format == target.FormatProgress {
+			println()
+		}
+	}()
+
+	if err := s.__requirementsHasSudo(); err != nil {
+		return nil, convertError(err, &ImagesServiceError{})
+	}
+	if err := s.__requirementsHasSudoPermission(); err != nil {
+		return nil, convertError(err, &ImagesServiceError{})
+	}
+
+	return s.imagesService()
+}
+
+// Status returns the status of the containers of the stack, and whether all
+// of them are running and none is unhealthy.
+func (s *Service) Status() ([]StatusData, bool, error) {
+	if err := s.__requirementsHasSudo(); err != nil {
+		return nil, false, convertError(err, &StatusServiceError{})
+	}
+	if err := s.__requirementsHasSudoPermission(); err != nil {
+		return nil, false, convertError(err, &StatusServiceError{})
+	}
+
+	return s.statusService()
+}
+
+// Logs writes the logs of the given components, all if none, prefixed by
+// the component to w. With Follow it streams until the context is done.
+func (s *Service) Logs(opts LogsOptions, w io.Writer) error {
+	if err := s.__requirementsHasSudo(); err != nil {
+		return convertError(err, &LogsServiceError{})
+	}
+	if err := s.__requirementsHasSudoPermission(); err != nil {
+		return convertError(err, &LogsServiceError{})
+	}
+
+	return s.logsService(opts, w)
+}
+
 func (s *Service) Doctor() (*[]Health, bool) {
 	return s.examineTarget()
 }
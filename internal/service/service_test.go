@@ -5,9 +5,17 @@ Licensed under the MIT License, see LICENSE file in the project root for details
 package service
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"io"
 	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -20,6 +28,8 @@ import (
 type track struct {
 	Timestamp string `json:"timestamp"`
 	Message   string `json:"message"`
+	Event     string `json:"event"`
+	Step      string `json:"step"`
 }
 
 func Test_Deploy(t *testing.T) {
@@ -40,7 +50,7 @@ func Test_Deploy(t *testing.T) {
 	assert.NoError(t, err, "deploy service")
 
 	tracks := strings.Split(record, "\n")
-	assert.Len(t, tracks, 58, "number of log lines")
+	assert.Len(t, tracks, 16, "number of log lines")
 
 	wanted := "Running 'command -v sudo' as .+@localhost"
 	assert.Regexp(t, wanted, tracks[0], "first log line")
@@ -68,12 +78,46 @@ func Test_Deploy(t *testing.T) {
 	var track track
 	err = json.Unmarshal([]byte(tracks[0]), &track)
 	assert.NoError(t, err, "unmarshal json output")
+	assert.Equal(t, "step_start", track.Event, "first log line event")
+	assert.Equal(t, "requirements.has-sudo", track.Step, "first log line step")
+
+	err = json.Unmarshal([]byte(tracks[1]), &track)
+	assert.NoError(t, err, "unmarshal json output")
 
 	wanted = "Running 'command -v sudo' as .+@localhost"
 	assert.Regexp(t, wanted, track.Message, "first log line")
 	assert.NotEmpty(t, track.Timestamp, "first log line timestamp")
 }
 
+func Test_DeployPlanExportsCredentials(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	plan := t.TempDir() + "/plan.sh"
+	s, err := New(context.Background(), Options{
+		Config:     &ServiceConfig{Hostname: "finch.example.com"},
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		PlanOut:    plan,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.__deployGenerateMTLSCertificates()
+	assert.NoError(t, err, "generate mTLS certificates")
+
+	_, err = config.LookupStack("finch.example.com")
+	assert.ErrorContains(t, err, "stack not found", "local config untouched")
+
+	script, err := os.ReadFile(plan)
+	assert.NoError(t, err, "read plan")
+	assert.Contains(t, string(script), "# After running the plan, import the client credentials with 'finchctl service import-credentials "+plan+".credentials.json'.\n", "import note")
+
+	name, err := config.ImportStack(plan + ".credentials.json")
+	assert.NoError(t, err, "import credentials")
+	assert.Equal(t, "finch.example.com", name, "stack name")
+}
+
 func Test_Teardown(t *testing.T) {
 	setupAssets(t)
 	defer teardownAssets(t)
@@ -92,7 +136,7 @@ func Test_Teardown(t *testing.T) {
 	assert.NoError(t, err, "teardown service")
 
 	tracks := strings.Split(record, "\n")
-	assert.Len(t, tracks, 9, "number of log lines mismatch")
+	assert.Len(t, tracks, 10, "number of log lines mismatch")
 
 	wanted := "Running 'command -v sudo' as .+@localhost"
 	assert.Regexp(t, wanted, tracks[0], "first log line")
@@ -127,6 +171,19 @@ func Test_Update(t *testing.T) {
 	setupAssets(t)
 	defer teardownAssets(t)
 
+	libDir := os.Getenv(ServiceLibEnv)
+	configs := map[string]string{
+		"loki/etc/loki.yaml":           "limits_config:\n  retention_period: 30d\n",
+		"mimir/etc/mimir.yaml":         "limits:\n  compactor_blocks_retention_period: 90d\n",
+		"pyroscope/etc/pyroscope.yaml": "limits:\n  compactor_blocks_retention_period: 7d\n",
+	}
+	for file, content := range configs {
+		err := os.MkdirAll(path.Dir(libDir+"/"+file), 0755)
+		assert.NoError(t, err, "create config dir")
+		err = os.WriteFile(libDir+"/"+file, []byte(content), 0600)
+		assert.NoError(t, err, "write "+file)
+	}
+
 	s, err := New(context.Background(), Options{
 		TargetURL:  "localhost",
 		Format:     target.FormatDocumentation,
@@ -140,8 +197,10 @@ func Test_Update(t *testing.T) {
 	})
 	assert.NoError(t, err, "update service")
 
+	assert.Equal(t, Retention{Logs: "30d", Metrics: "90d", Profiles: "7d"}, s.config.Retention, "retention kept")
+
 	tracks := strings.Split(record, "\n")
-	assert.Len(t, tracks, 55, "number of log lines")
+	assert.Len(t, tracks, 22, "number of log lines")
 
 	wanted := "Running 'command -v sudo' as .+@localhost"
 	assert.Regexp(t, wanted, tracks[0], "first log line")
@@ -166,6 +225,11 @@ func Test_Update(t *testing.T) {
 	var track track
 	err = json.Unmarshal([]byte(tracks[0]), &track)
 	assert.NoError(t, err, "unmarshal json output")
+	assert.Equal(t, "step_start", track.Event, "first log line event")
+	assert.Equal(t, "requirements.has-sudo", track.Step, "first log line step")
+
+	err = json.Unmarshal([]byte(tracks[1]), &track)
+	assert.NoError(t, err, "unmarshal json output")
 
 	wanted = "Running 'command -v sudo' as .+@localhost"
 	assert.Regexp(t, wanted, track.Message, "first log line")
@@ -190,7 +254,7 @@ func Test_RotateSecret(t *testing.T) {
 	assert.NoError(t, err, "rotate secret")
 
 	tracks := strings.Split(record, "\n")
-	assert.Len(t, tracks, 10, "number of log lines mismatch")
+	assert.Len(t, tracks, 11, "number of log lines mismatch")
 
 	wanted := "Running 'command -v sudo' as .+@localhost"
 	assert.Regexp(t, wanted, tracks[0], "first log line")
@@ -220,6 +284,52 @@ func Test_RotateSecret(t *testing.T) {
 	assert.Regexp(t, wanted, tracks[0], "first log line")
 }
 
+func Test_RotateSecretPlan(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	libDir := os.Getenv(ServiceLibEnv)
+	finch := `{ "created_at": "2026-09-01T08:12:44Z", "id": "rid:finch:2f8a", "database": "sqlite://finch.db", "secret": "old", "hostname": "localhost" }`
+	err := os.WriteFile(libDir+"/finch.json", []byte(finch), 0600)
+	assert.NoError(t, err, "write finch.json")
+
+	plan := t.TempDir() + "/plan.sh"
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		PlanOut:    plan,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.rotateServiceSecret()
+	assert.NoError(t, err, "plan rotate secret")
+
+	script, err := os.ReadFile(plan)
+	assert.NoError(t, err, "read plan")
+	assert.Contains(t, string(script), "# Queried while planning: test -e "+libDir+"/traefik/etc/conf.d/letsencrypt.yaml\n", "probe not repeated")
+
+	bin := t.TempDir()
+	err = os.WriteFile(bin+"/docker", []byte("#!/bin/sh\nexit 0\n"), 0755)
+	assert.NoError(t, err, "write fake docker")
+	c := exec.Command("sh", plan)
+	c.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+	out, err := c.CombinedOutput()
+	assert.NoError(t, err, "run plan: %s", out)
+
+	content, err := os.ReadFile(libDir + "/finch.json")
+	assert.NoError(t, err, "read installed finch.json")
+	var cfg FinchConfig
+	err = json.Unmarshal(content, &cfg)
+	assert.NoError(t, err, "unmarshal installed finch.json")
+	assert.Equal(t, "rid:finch:2f8a", cfg.Id, "stack ID kept")
+	assert.Equal(t, "sqlite://finch.db", cfg.Database, "database kept")
+	assert.Equal(t, "localhost", cfg.Hostname, "hostname kept")
+	assert.Equal(t, "2026-09-01T08:12:44Z", cfg.CreatedAt, "creation time kept")
+	assert.NotEqual(t, "old", cfg.Secret, "secret rotated")
+	assert.NotEmpty(t, cfg.Secret, "secret set")
+}
+
 func Test_RotateCertificate(t *testing.T) {
 	setupAssets(t)
 	defer teardownAssets(t)
@@ -238,7 +348,7 @@ func Test_RotateCertificate(t *testing.T) {
 	assert.NoError(t, err, "rotate certificate")
 
 	tracks := strings.Split(record, "\n")
-	assert.Len(t, tracks, 9, "number of log lines mismatch")
+	assert.Len(t, tracks, 10, "number of log lines mismatch")
 
 	wanted := "Running 'command -v sudo' as .+@localhost"
 	assert.Regexp(t, wanted, tracks[0], "first log line")
@@ -294,7 +404,7 @@ func Test_Register(t *testing.T) {
 	assert.NoError(t, err, "register service")
 
 	tracks := strings.Split(record, "\n")
-	assert.Len(t, tracks, 8, "number of log lines mismatch")
+	assert.Len(t, tracks, 9, "number of log lines mismatch")
 
 	wanted := "Running 'command -v sudo' as .+@localhost"
 	assert.Regexp(t, wanted, tracks[0], "first log line")
@@ -343,7 +453,7 @@ func Test_Deregister(t *testing.T) {
 	assert.NoError(t, err, "deregister service")
 
 	tracks := strings.Split(record, "\n")
-	assert.Len(t, tracks, 8, "number of log lines mismatch")
+	assert.Len(t, tracks, 9, "number of log lines mismatch")
 
 	wanted := "Running 'command -v sudo' as .+@localhost"
 	assert.Regexp(t, wanted, tracks[0], "first log line")
@@ -374,6 +484,288 @@ func Test_Deregister(t *testing.T) {
 	assert.NotEmpty(t, track.Timestamp, "first log line timestamp")
 }
 
+func Test_Backup(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	libDir := os.Getenv(ServiceLibEnv)
+	err := os.WriteFile(libDir+"/finch.json", []byte(`{ "hostname": "localhost", "id": "f1c4e2a9b3d70e55" }`), 0600)
+	assert.NoError(t, err, "write finch.json")
+	err = os.MkdirAll(libDir+"/loki/etc", 0755)
+	assert.NoError(t, err, "create loki dir")
+	err = os.WriteFile(libDir+"/loki/etc/loki.yaml", []byte("auth_enabled: false\n"), 0600)
+	assert.NoError(t, err, "write loki.yaml")
+
+	bin := t.TempDir()
+	docker := "#!/bin/sh\ncase \"$*\" in *\"config --images\"*) echo grafana/loki:3.7.4; echo ghcr.io/tschaefer/finch:1.13.1;; esac\n"
+	err = os.WriteFile(bin+"/docker", []byte(docker), 0755)
+	assert.NoError(t, err, "write fake docker")
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	output := t.TempDir() + "/finch-backup.tar.gz"
+	err = s.Backup(BackupOptions{Output: output, Components: []string{"logs"}})
+	assert.NoError(t, err, "backup service")
+
+	f, err := os.Open(output)
+	assert.NoError(t, err, "open archive")
+	defer func() {
+		_ = f.Close()
+	}()
+	gz, err := gzip.NewReader(f)
+	assert.NoError(t, err, "read gzip")
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err, "read tar")
+		content, _ := io.ReadAll(tr)
+		files[hdr.Name] = content
+	}
+
+	var manifest BackupManifest
+	err = json.Unmarshal(files["manifest.json"], &manifest)
+	assert.NoError(t, err, "unmarshal manifest")
+	assert.Equal(t, "f1c4e2a9b3d70e55", manifest.StackID, "stack id")
+	assert.Equal(t, []string{"config", "logs"}, manifest.Components, "components")
+	assert.Equal(t, []string{"ghcr.io/tschaefer/finch:1.13.1", "grafana/loki:3.7.4"}, manifest.Images, "images")
+
+	sum := sha256.Sum256([]byte("auth_enabled: false\n"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), manifest.Checksums["loki/etc/loki.yaml"], "checksum")
+	assert.Contains(t, files, "loki/etc/loki.yaml", "archived config")
+	assert.Contains(t, files, "finch.json", "archived finch config")
+
+	staged, err := filepath.Glob(libDir + "/.backup-*")
+	assert.NoError(t, err, "glob staging dirs")
+	assert.Empty(t, staged, "staging dir removed")
+
+	err = s.Backup(BackupOptions{Output: "finch-backup.zip"})
+	assert.ErrorContains(t, err, "unsupported archive format", "archive format")
+}
+
+func Test_BackupPaths(t *testing.T) {
+	paths, services := backupPaths([]string{"config"})
+	assert.Contains(t, paths, "grafana/dashboards", "grafana config archived")
+	assert.Equal(t, []string{"finch", "grafana"}, services, "services writing the config stopped")
+
+	paths, services = backupPaths([]string{"config", "grafana"})
+	assert.Contains(t, paths, "grafana", "grafana archived")
+	assert.NotContains(t, paths, "grafana/dashboards", "path within grafana left out")
+	assert.NotContains(t, paths, "grafana/alerting", "path within grafana left out")
+	assert.Equal(t, []string{"finch", "grafana"}, services, "services stopped once")
+}
+
+func Test_Restore(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	libDir := os.Getenv(ServiceLibEnv)
+	err := os.WriteFile(libDir+"/finch.json", []byte(`{ "hostname": "localhost", "id": "f1c4e2a9b3d70e55" }`), 0600)
+	assert.NoError(t, err, "write finch.json")
+	err = os.WriteFile(libDir+"/finch.db", []byte("agents"), 0600)
+	assert.NoError(t, err, "write finch.db")
+
+	bin := t.TempDir()
+	docker := "#!/bin/sh\ncase \"$*\" in *inspect*) echo healthy;; esac\n"
+	err = os.WriteFile(bin+"/docker", []byte(docker), 0755)
+	assert.NoError(t, err, "write fake docker")
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	archive := t.TempDir() + "/finch-backup.tar.gz"
+	err = s.Backup(BackupOptions{Output: archive})
+	assert.NoError(t, err, "backup service")
+
+	err = s.restoreService(RestoreOptions{From: archive})
+	assert.ErrorContains(t, err, "service already deployed on target", "restore over deployed service")
+
+	restoreDir := t.TempDir()
+	t.Setenv(ServiceLibEnv, restoreDir)
+	err = s.restoreService(RestoreOptions{From: archive})
+	assert.NoError(t, err, "restore service")
+
+	content, err := os.ReadFile(restoreDir + "/finch.db")
+	assert.NoError(t, err, "read restored finch.db")
+	assert.Equal(t, "agents", string(content), "restored finch.db")
+	assert.NoFileExists(t, restoreDir+"/"+backupManifestFile, "manifest not restored")
+	assert.DirExists(t, restoreDir+"/loki/data", "dir hierarchy")
+
+	stack, err := config.LookupStack("localhost")
+	assert.NoError(t, err, "lookup stack")
+	assert.NotEmpty(t, stack.Cert, "stack certificate")
+}
+
+func Test_RestoreCorruptArchive(t *testing.T) {
+	setupAssets(t)
+	defer teardownAssets(t)
+
+	libDir := os.Getenv(ServiceLibEnv)
+	err := os.WriteFile(libDir+"/finch.db", []byte("agents"), 0600)
+	assert.NoError(t, err, "write finch.db")
+
+	bin := t.TempDir()
+	docker := "#!/bin/sh\ncase \"$*\" in *inspect*) echo healthy;; esac\n"
+	err = os.WriteFile(bin+"/docker", []byte(docker), 0755)
+	assert.NoError(t, err, "write fake docker")
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	archive := t.TempDir() + "/finch-backup.tar.gz"
+	err = s.Backup(BackupOptions{Output: archive})
+	assert.NoError(t, err, "backup service")
+
+	unpacked := t.TempDir()
+	out, err := exec.Command("tar", "-xzf", archive, "-C", unpacked).CombinedOutput()
+	assert.NoError(t, err, "unpack archive: %s", out)
+	err = os.WriteFile(unpacked+"/finch.db", []byte("tampered"), 0600)
+	assert.NoError(t, err, "tamper finch.db")
+	corrupt := t.TempDir() + "/finch-backup.tar.gz"
+	entries, err := os.ReadDir(unpacked)
+	assert.NoError(t, err, "list unpacked archive")
+	args := []string{"-czf", corrupt, "-C", unpacked}
+	for _, entry := range entries {
+		args = append(args, entry.Name())
+	}
+	out, err = exec.Command("tar", args...).CombinedOutput()
+	assert.NoError(t, err, "pack corrupt archive: %s", out)
+
+	restoreDir := t.TempDir()
+	t.Setenv(ServiceLibEnv, restoreDir)
+	err = s.restoreService(RestoreOptions{From: corrupt})
+	assert.ErrorContains(t, err, "checksum verification failed", "corrupt archive")
+	assert.NoFileExists(t, restoreDir+"/finch.json", "nothing restored")
+
+	err = s.restoreService(RestoreOptions{From: archive})
+	assert.NoError(t, err, "restore retried")
+	content, err := os.ReadFile(restoreDir + "/finch.db")
+	assert.NoError(t, err, "read restored finch.db")
+	assert.Equal(t, "agents", string(content), "restored finch.db")
+
+	err = os.WriteFile(bin+"/sudo", []byte("#!/bin/sh\nexit 2\n"), 0755)
+	assert.NoError(t, err, "write failing sudo")
+	t.Setenv(ServiceLibEnv, t.TempDir())
+	err = s.__restoreIsDeployed()
+	assert.Error(t, err, "sudo failure is no missing service")
+	assert.NotContains(t, err.Error(), "already deployed", "sudo failure")
+}
+
+func replay(t *testing.T, s *Service, fixture string) {
+	r, err := target.NewReplay("testdata/"+fixture, target.Options{Format: target.FormatQuiet})
+	assert.NoError(t, err, "load fixture")
+	s.target = r
+}
+
+func Test_DeployReplay(t *testing.T) {
+	t.Setenv(ServiceLibEnv, "")
+	t.Setenv(config.ConfigLocationEnv, t.TempDir())
+
+	interval := readinessInterval
+	readinessInterval = 10 * time.Millisecond
+	defer func() { readinessInterval = interval }()
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+		Config: &ServiceConfig{
+			Hostname: "finch.example.com",
+		},
+	})
+	assert.NoError(t, err, "create service")
+
+	replay(t, s, "deploy.jsonl")
+	err = s.Deploy()
+	assert.NoError(t, err, "deploy service")
+
+	stack, err := config.LookupStack("finch.example.com")
+	assert.NoError(t, err, "lookup stack")
+	assert.NotEmpty(t, stack.Cert, "stack certificate")
+
+	replay(t, s, "deploy-unhealthy.jsonl")
+	err = s.Deploy()
+	assert.Error(t, err, "deploy service")
+	assert.IsType(t, &DeployServiceError{}, err, "error type")
+	assert.ErrorContains(t, err, "readiness check failed for: hc-loki", "error message")
+	assert.ErrorContains(t, err, "permission denied", "error reason")
+}
+
+func Test_UpdateRollbackReplay(t *testing.T) {
+	t.Setenv(ServiceLibEnv, "")
+	cfgDir := t.TempDir()
+	t.Setenv(config.ConfigLocationEnv, cfgDir)
+	err := os.WriteFile(cfgDir+"/finch.json", []byte(`{ "stacks": [ { "name": "finch.example.com" } ] }`), 0600)
+	assert.NoError(t, err, "write finch.json")
+
+	interval := readinessInterval
+	readinessInterval = 10 * time.Millisecond
+	defer func() { readinessInterval = interval }()
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	replay(t, s, "update-rollback.jsonl")
+	err = s.Update()
+	assert.Error(t, err, "update service")
+	assert.IsType(t, &UpdateServiceError{}, err, "error type")
+	assert.ErrorContains(t, err, "readiness check failed for: hc-loki, rolled back to the previous version", "error message")
+	assert.ErrorContains(t, err, "permission denied", "error reason")
+	assert.Equal(t, "ops@example.com", s.config.LetsEncrypt.Email, "letsencrypt email from stack manifest")
+	assert.Equal(t, "30d", s.config.Retention.Logs, "retention from stack manifest")
+}
+
+func Test_DoctorReplay(t *testing.T) {
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	replay(t, s, "doctor.jsonl")
+	list, ok := s.Doctor()
+	assert.False(t, ok, "doctor result")
+
+	status := map[string]bool{}
+	for _, h := range *list {
+		status[h.Requirement] = h.Ok
+	}
+	assert.Equal(t, map[string]bool{
+		"os":                   true,
+		"sudo":                 true,
+		"superuser permission": true,
+		"curl":                 true,
+		"GitHub connection":    true,
+		"port 80":              true,
+		"port 443":             false,
+	}, status, "health checks")
+}
+
 func capture(f func()) string {
 	originalStdout := os.Stdout
 
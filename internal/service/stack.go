@@ -0,0 +1,87 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/tschaefer/finchctl/internal/target"
+	"github.com/tschaefer/finchctl/internal/version"
+)
+
+const (
+	stackManifestFile    = "stack.json"
+	stackManifestVersion = 1
+)
+
+// StackManifest records the options a stack was deployed with, so later
+// operations reproduce the deployment. The custom TLS files are local to the
+// deploying workstation and not recorded.
+type StackManifest struct {
+	Version   int           `json:"version"`
+	CreatedAt string        `json:"created_at"`
+	UpdatedAt string        `json:"updated_at"`
+	Release   string        `json:"release"`
+	Config    ServiceConfig `json:"config"`
+}
+
+// __stackReadManifest reads the stack manifest of the target, it returns nil
+// if the stack was deployed without one.
+func (s *Service) __stackReadManifest() (*StackManifest, error) {
+	file := path.Join(s.libDir(), stackManifestFile)
+	out, err := s.target.RunForce(s.ctx, "sudo test -e "+file)
+	if err != nil {
+		var exitErr *target.ExitError
+		if errors.As(err, &exitErr) && exitErr.Status == 1 {
+			return nil, nil
+		}
+		return nil, &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	out, err = s.target.RunForce(s.ctx, "sudo cat "+file)
+	if err != nil {
+		return nil, &UpdateServiceError{Message: "failed to read stack manifest", Reason: string(out), Err: err}
+	}
+
+	var manifest StackManifest
+	if err := json.Unmarshal(out, &manifest); err != nil {
+		return nil, &UpdateServiceError{Message: "failed to read stack manifest", Reason: err.Error()}
+	}
+	if manifest.Version > stackManifestVersion {
+		return nil, &UpdateServiceError{
+			Message: fmt.Sprintf("stack manifest version %d is not supported", manifest.Version),
+			Reason:  "update finchctl",
+		}
+	}
+
+	return &manifest, nil
+}
+
+func (s *Service) __stackWriteManifest() error {
+	now := time.Now().Format(time.RFC3339)
+	manifest := StackManifest{
+		Version:   stackManifestVersion,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Release:   version.Release(),
+		Config:    *s.config,
+	}
+	manifest.Config.CustomTLS.CertFilePath = ""
+	manifest.Config.CustomTLS.KeyFilePath = ""
+	if s.manifest != nil {
+		manifest.CreatedAt = s.manifest.CreatedAt
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return &DeployServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	return s.__helperCopyContent(path.Join(s.libDir(), stackManifestFile), "400", "0:0", append(data, '\n'))
+}
@@ -0,0 +1,93 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func Test_StackManifestRoundTrip(t *testing.T) {
+	t.Setenv(ServiceLibEnv, t.TempDir())
+
+	config := &ServiceConfig{Hostname: "finch.example.com"}
+	config.LetsEncrypt.Enabled = true
+	config.LetsEncrypt.Email = "ops@example.com"
+	config.Retention = Retention{Logs: "30d", Metrics: "90d", Profiles: "7d"}
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+		Config:     config,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.__stackWriteManifest()
+	assert.NoError(t, err, "write stack manifest")
+
+	s.config = &ServiceConfig{}
+	err = s.__updateSetTargetConfiguration()
+	assert.NoError(t, err, "set target configuration")
+	assert.Equal(t, config, s.config, "deploy-time configuration")
+	assert.NotEmpty(t, s.manifest.CreatedAt, "created at")
+
+	err = os.WriteFile(os.Getenv(ServiceLibEnv)+"/"+stackManifestFile, []byte(`{ "version": 2 }`), 0600)
+	assert.NoError(t, err, "write newer stack manifest")
+	err = s.__updateSetTargetConfiguration()
+	assert.ErrorContains(t, err, "stack manifest version 2 is not supported", "newer manifest")
+}
+
+func Test_StackManifestMissingOrUnreadable(t *testing.T) {
+	t.Setenv(ServiceLibEnv, t.TempDir())
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	manifest, err := s.__stackReadManifest()
+	assert.NoError(t, err, "missing manifest")
+	assert.Nil(t, manifest, "no manifest")
+
+	err = os.Mkdir(os.Getenv(ServiceLibEnv)+"/"+stackManifestFile, 0700)
+	assert.NoError(t, err, "create unreadable manifest")
+	_, err = s.__stackReadManifest()
+	assert.ErrorContains(t, err, "failed to read stack manifest", "unreadable manifest")
+}
+
+func Test_StackManifestOmitsCustomTLSFiles(t *testing.T) {
+	t.Setenv(ServiceLibEnv, t.TempDir())
+
+	config := &ServiceConfig{Hostname: "finch.example.com"}
+	config.CustomTLS.Enabled = true
+	config.CustomTLS.CertFilePath = "/home/ops/cert.pem"
+	config.CustomTLS.KeyFilePath = "/home/ops/key.pem"
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+		Config:     config,
+	})
+	assert.NoError(t, err, "create service")
+
+	err = s.__stackWriteManifest()
+	assert.NoError(t, err, "write stack manifest")
+
+	s.config = &ServiceConfig{}
+	err = s.__updateSetTargetConfiguration()
+	assert.NoError(t, err, "set target configuration")
+	assert.True(t, s.config.CustomTLS.Enabled, "custom TLS enabled")
+	assert.Empty(t, s.config.CustomTLS.CertFilePath, "certificate file not recorded")
+	assert.Empty(t, s.config.CustomTLS.KeyFilePath, "key file not recorded")
+}
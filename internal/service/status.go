@@ -0,0 +1,171 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type StatusData struct {
+	Service   string `json:"service"`
+	Container string `json:"container"`
+	State     string `json:"state"`
+	Health    string `json:"health"`
+	StartedAt string `json:"started_at"`
+	Uptime    string `json:"uptime"`
+	Restarts  int    `json:"restarts"`
+	Image     string `json:"image"`
+	Ok        bool   `json:"ok"`
+}
+
+type composeContainer struct {
+	ID      string `json:"ID"`
+	Name    string `json:"Name"`
+	Service string `json:"Service"`
+	Image   string `json:"Image"`
+	State   string `json:"State"`
+	Health  string `json:"Health"`
+}
+
+// parseComposePs parses the output of docker compose ps --format json, a JSON
+// array up to Compose 2.20 and a JSON object per line since.
+func parseComposePs(out []byte) ([]composeContainer, error) {
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, nil
+	}
+
+	var containers []composeContainer
+	if out[0] == '[' {
+		err := json.Unmarshal(out, &containers)
+		return containers, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var c composeContainer
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, err
+		}
+		containers = append(containers, c)
+	}
+
+	return containers, scanner.Err()
+}
+
+// __statusInspect returns the restart count and start time of the containers
+// by their ID.
+func (s *Service) __statusInspect(containers []composeContainer) (map[string][2]string, error) {
+	inspected := map[string][2]string{}
+	if len(containers) == 0 {
+		return inspected, nil
+	}
+
+	var ids []string
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+	out, err := s.target.RunForce(s.ctx, "sudo docker inspect --format '{{.Id}} {{.RestartCount}} {{.State.StartedAt}}' "+strings.Join(ids, " "))
+	if err != nil {
+		return nil, &StatusServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		for _, c := range containers {
+			if c.ID != "" && strings.HasPrefix(fields[0], c.ID) {
+				inspected[c.ID] = [2]string{fields[1], fields[2]}
+			}
+		}
+	}
+
+	return inspected, nil
+}
+
+func (s *Service) statusService() ([]StatusData, bool, error) {
+	compose := "sudo docker compose --file " + path.Join(s.libDir(), "docker-compose.yaml")
+
+	out, err := s.target.RunForce(s.ctx, compose+" config --services")
+	if err != nil {
+		return nil, false, &StatusServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+	services := strings.Fields(string(out))
+
+	out, err = s.target.RunForce(s.ctx, compose+" ps --all --format json")
+	if err != nil {
+		return nil, false, &StatusServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+	containers, err := parseComposePs(out)
+	if err != nil {
+		return nil, false, &StatusServiceError{Message: "failed to parse compose status", Reason: err.Error()}
+	}
+
+	inspected, err := s.__statusInspect(containers)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var list []StatusData
+	ok := true
+	for _, c := range containers {
+		status := StatusData{
+			Service:   c.Service,
+			Container: c.Name,
+			State:     c.State,
+			Health:    c.Health,
+			Image:     c.Image,
+			Ok:        c.State == "running" && c.Health != "unhealthy",
+		}
+		if i, found := inspected[c.ID]; found {
+			status.Restarts, _ = strconv.Atoi(i[0])
+			status.StartedAt = i[1]
+			started, err := time.Parse(time.RFC3339Nano, i[1])
+			if err == nil && c.State == "running" {
+				status.Uptime = time.Since(started).Truncate(time.Second).String()
+			}
+		}
+		ok = ok && status.Ok
+		list = append(list, status)
+	}
+
+	for _, service := range services {
+		if !slices.ContainsFunc(list, func(d StatusData) bool { return d.Service == service }) {
+			list = append(list, StatusData{Service: service, State: "missing"})
+			ok = false
+		}
+	}
+
+	// Each component is followed by its health check sidecar.
+	slices.SortFunc(list, func(a, b StatusData) int {
+		ka, kb := strings.TrimPrefix(a.Service, "hc-"), strings.TrimPrefix(b.Service, "hc-")
+		if ka != kb {
+			return strings.Compare(ka, kb)
+		}
+		ha, hb := strings.HasPrefix(a.Service, "hc-"), strings.HasPrefix(b.Service, "hc-")
+		switch {
+		case ha && !hb:
+			return 1
+		case !ha && hb:
+			return -1
+		}
+		return strings.Compare(a.Container, b.Container)
+	})
+
+	return list, ok, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tschaefer/finchctl/internal/target"
+)
+
+func Test_ParseComposePs(t *testing.T) {
+	lines := `{"ID":"a1","Name":"finch","Service":"finch","State":"running","Health":"healthy"}
+{"ID":"b2","Name":"loki","Service":"loki","State":"exited","Health":""}
+`
+	containers, err := parseComposePs([]byte(lines))
+	assert.NoError(t, err, "parse JSON lines")
+	assert.Len(t, containers, 2, "containers")
+	assert.Equal(t, "exited", containers[1].State, "state")
+
+	array := `[{"ID":"a1","Name":"finch","Service":"finch","State":"running","Health":"healthy"}]`
+	containers, err = parseComposePs([]byte(array))
+	assert.NoError(t, err, "parse JSON array")
+	assert.Len(t, containers, 1, "containers")
+
+	containers, err = parseComposePs([]byte("\n"))
+	assert.NoError(t, err, "parse empty output")
+	assert.Empty(t, containers, "no containers")
+}
+
+func Test_Status(t *testing.T) {
+	bin := t.TempDir()
+	docker := `#!/bin/sh
+case "$*" in
+*"config --services"*) printf 'finch\nhc-finch\nloki\nhc-loki\n' ;;
+*"ps --all --format json"*)
+	echo '{"ID":"a1","Name":"finch","Service":"finch","Image":"ghcr.io/tschaefer/finch:1.13.1","State":"running","Health":"healthy"}'
+	echo '{"ID":"b2","Name":"hc-finch","Service":"hc-finch","Image":"curlimages/curl:8.21.0","State":"running","Health":""}'
+	echo '{"ID":"c3","Name":"loki","Service":"loki","Image":"grafana/loki:3.7.4","State":"running","Health":"unhealthy"}' ;;
+inspect*) echo "a1f0 0 $(date -u +%Y-%m-%dT%H:%M:%SZ)"; echo "b2f0 0 $(date -u +%Y-%m-%dT%H:%M:%SZ)"; echo "c3f0 4 $(date -u +%Y-%m-%dT%H:%M:%SZ)" ;;
+esac
+`
+	err := os.WriteFile(bin+"/docker", []byte(docker), 0755)
+	assert.NoError(t, err, "write fake docker")
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+
+	s, err := New(context.Background(), Options{
+		TargetURL:  "localhost",
+		Format:     target.FormatQuiet,
+		CmdTimeout: 300 * time.Second,
+	})
+	assert.NoError(t, err, "create service")
+
+	list, ok, err := s.Status()
+	assert.NoError(t, err, "get status")
+	assert.False(t, ok, "unhealthy and missing containers")
+	assert.Len(t, list, 4, "services")
+
+	assert.Equal(t, "finch", list[0].Service, "component first")
+	assert.Equal(t, "hc-finch", list[1].Service, "sidecar follows component")
+	assert.True(t, list[0].Ok, "healthy container")
+	assert.NotEmpty(t, list[0].Uptime, "uptime")
+
+	assert.Equal(t, "loki", list[2].Service, "component")
+	assert.Equal(t, 4, list[2].Restarts, "restarts")
+	assert.False(t, list[2].Ok, "unhealthy container")
+
+	assert.Equal(t, "hc-loki", list[3].Service, "missing sidecar")
+	assert.Equal(t, "missing", list[3].State, "missing state")
+}
@@ -16,17 +16,17 @@ func (s *Service) teardownService() error {
 	}
 
 	if _, err := config.LookupStack(s.config.Hostname); err != nil {
-		return &TeardownServiceError{Message: err.Error(), Reason: ""}
+		return &TeardownServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	out, err := s.target.Run(s.ctx, "sudo docker compose --file "+path.Join(s.libDir(), "docker-compose.yaml")+" down --volumes")
 	if err != nil {
-		return &TeardownServiceError{Message: err.Error(), Reason: string(out)}
+		return &TeardownServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	out, err = s.target.Run(s.ctx, "sudo rm -rf "+s.libDir())
 	if err != nil {
-		return &TeardownServiceError{Message: err.Error(), Reason: string(out)}
+		return &TeardownServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	if s.dryRun {
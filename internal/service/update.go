@@ -6,44 +6,99 @@ package service
 
 import (
 	"encoding/json"
+	"fmt"
+	"maps"
 	"path"
+	"strings"
 
+	"github.com/goccy/go-yaml"
 	"github.com/tschaefer/finchctl/internal/config"
 )
 
+// __updateSetTargetConfiguration sets the configuration the stack was
+// deployed with. Without a stack manifest it is derived from the deployed
+// files, the Let's Encrypt email and custom TLS settings are unknown then.
 func (s *Service) __updateSetTargetConfiguration() error {
+	manifest, err := s.__stackReadManifest()
+	if err != nil {
+		return err
+	}
+	if manifest != nil {
+		// Custom TLS files and image overrides given on the command line
+		// take precedence over the deployed ones. Without custom TLS files
+		// the deployed pair is kept.
+		customTLS := s.config.CustomTLS
+		images := s.config.Images
+		*s.config = manifest.Config
+		s.config.CustomTLS.CertFilePath = ""
+		s.config.CustomTLS.KeyFilePath = ""
+		if customTLS.Enabled {
+			s.config.CustomTLS = customTLS
+		}
+		if len(images) > 0 {
+			if s.config.Images == nil {
+				s.config.Images = map[string]string{}
+			}
+			maps.Copy(s.config.Images, images)
+		}
+		s.manifest = manifest
+		return nil
+	}
+
 	cfgPath := path.Join(s.libDir(), "finch.json")
 	out, err := s.target.RunForce(s.ctx, "sudo cat "+cfgPath)
 	if err != nil {
-		return &UpdateServiceError{Message: err.Error(), Reason: string(out)}
+		return &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	letsencrypt := false
-	yaml := path.Join(s.libDir(), "traefik/etc/conf.d/letsencrypt.yaml")
-	if _, err = s.target.Run(s.ctx, "test -e "+yaml); err == nil {
+	letsencryptConfig := path.Join(s.libDir(), "traefik/etc/conf.d/letsencrypt.yaml")
+	if _, err = s.target.RunForce(s.ctx, "test -e "+letsencryptConfig); err == nil {
 		letsencrypt = true
 	}
 
 	var cfg FinchConfig
 	if err = json.Unmarshal([]byte(out), &cfg); err != nil {
-		return &UpdateServiceError{Message: err.Error(), Reason: ""}
+		return &UpdateServiceError{Message: err.Error(), Reason: "", Err: err}
 	}
 
 	s.config.Hostname = cfg.Hostname
 	s.config.LetsEncrypt.Enabled = letsencrypt
+	if letsencrypt {
+		s.config.LetsEncrypt.Email = s.__updateReadLetsEncryptEmail()
+	}
 
 	return nil
 }
 
-func (s *Service) __updateRecomposeDockerServices() error {
-	err := s.__deployCopyComposeFile()
+// __updateReadLetsEncryptEmail returns the Let's Encrypt email of the
+// deployed Traefik config, empty if it is unknown.
+func (s *Service) __updateReadLetsEncryptEmail() string {
+	out, err := s.target.RunForce(s.ctx, "sudo cat "+path.Join(s.libDir(), "traefik/etc/traefik.yaml"))
 	if err != nil {
-		return convertError(err, &UpdateServiceError{})
+		return ""
+	}
+
+	var cfg struct {
+		Resolvers struct {
+			LetsEncrypt struct {
+				Acme struct {
+					Email string `yaml:"email"`
+				} `yaml:"acme"`
+			} `yaml:"letsencrypt"`
+		} `yaml:"certificatesresolvers"`
 	}
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		return ""
+	}
+
+	return cfg.Resolvers.LetsEncrypt.Acme.Email
+}
 
+func (s *Service) __updateRecomposeDockerServices() error {
 	out, err := s.target.Run(s.ctx, "sudo docker compose --file "+path.Join(s.libDir(), "docker-compose.yaml")+" pull --policy missing")
 	if err != nil {
-		return &UpdateServiceError{Message: err.Error(), Reason: string(out)}
+		return &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
 	}
 
 	err = s.__deployComposeUp()
@@ -55,64 +110,215 @@ func (s *Service) __updateRecomposeDockerServices() error {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	out, err = s.target.Run(s.ctx, "sudo docker image prune --force")
+	return nil
+}
+
+func (s *Service) __updatePruneImages() error {
+	out, err := s.target.Run(s.ctx, "sudo docker image prune --force")
 	if err != nil {
-		return &UpdateServiceError{Message: err.Error(), Reason: string(out)}
+		return &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	return nil
+}
+
+const rollbackArchive = ".rollback.tar"
+
+// rollbackPaths are the paths below the lib directory rewritten by an update.
+var rollbackPaths = []string{
+	"docker-compose.yaml",
+	"stack.json",
+	"alloy/etc",
+	"grafana/alerting",
+	"grafana/dashboards",
+	"loki/etc",
+	"mimir/etc",
+	"pyroscope/etc",
+	"traefik/etc",
+}
+
+// __updateSaveRollback archives the files rewritten by an update and returns
+// the images of the running containers as "reference ID" pairs. The IDs keep
+// the previous images even if a reference is moved by the update.
+func (s *Service) __updateSaveRollback() ([]string, error) {
+	cmd := fmt.Sprintf("sudo tar --ignore-failed-read -cpf %s -C %s %s", path.Join(s.libDir(), rollbackArchive), s.libDir(), strings.Join(rollbackPaths, " "))
+	if out, err := s.target.Run(s.ctx, cmd); err != nil {
+		return nil, &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	out, err := s.target.Run(s.ctx, "sudo docker compose --file "+path.Join(s.libDir(), "docker-compose.yaml")+" ps --all --quiet")
+	if err != nil {
+		return nil, &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+	containers := strings.Fields(string(out))
+	if len(containers) == 0 {
+		return nil, nil
+	}
+
+	out, err = s.target.Run(s.ctx, "sudo docker inspect --format '{{.Config.Image}} {{.Image}}' "+strings.Join(containers, " "))
+	if err != nil {
+		return nil, &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	var images []string
+	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+		if len(strings.Fields(line)) == 2 {
+			images = append(images, line)
+		}
+	}
+
+	return images, nil
+}
+
+func (s *Service) __updateRollback(images []string) error {
+	var paths []string
+	for _, p := range rollbackPaths {
+		paths = append(paths, path.Join(s.libDir(), p))
+	}
+
+	cmd := fmt.Sprintf("sudo rm -rf %s && sudo tar -xpf %s -C %s", strings.Join(paths, " "), path.Join(s.libDir(), rollbackArchive), s.libDir())
+	if out, err := s.target.Run(s.ctx, cmd); err != nil {
+		return &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
+	}
+
+	for _, image := range images {
+		ref, id, _ := strings.Cut(image, " ")
+		if out, err := s.target.Run(s.ctx, fmt.Sprintf("sudo docker tag %s %s", id, ref)); err != nil {
+			return &UpdateServiceError{Message: err.Error(), Reason: string(out), Err: err}
+		}
+	}
+
+	if err := s.__deployComposeUp(); err != nil {
+		return convertError(err, &UpdateServiceError{})
+	}
+
+	if err := s.__deployComposeReady(); err != nil {
+		return convertError(err, &UpdateServiceError{})
 	}
 
 	return nil
 }
 
+// __updateRolledBack reports the failed update err, which was rolled back
+// unless rollbackErr is set.
+func (s *Service) __updateRolledBack(err, rollbackErr error) error {
+	uerr, ok := convertError(err, &UpdateServiceError{}).(*UpdateServiceError)
+	if !ok {
+		uerr = &UpdateServiceError{Message: err.Error(), Reason: "", Err: err}
+	}
+
+	if rollbackErr != nil {
+		uerr.Message += fmt.Sprintf(", rollback failed, previous configuration kept in %s: %s",
+			path.Join(s.libDir(), rollbackArchive), rollbackErr.Error())
+		return uerr
+	}
+	uerr.Message += ", rolled back to the previous version"
+
+	return uerr
+}
+
 func (s *Service) updateService() error {
-	if err := s.__updateSetTargetConfiguration(); err != nil {
+	if err := s.tracker.Step("update.set-target-configuration", s.__updateSetTargetConfiguration); err != nil {
 		return err
 	}
 
+	// Images loaded from an archive have no digest, Compose would pull the
+	// pinned images on the target.
+	if s.offline && len(s.config.Pins) > 0 {
+		return &UpdateServiceError{Message: "--offline cannot be used with a stack pinned by digest", Reason: ""}
+	}
+
 	if _, err := config.LookupStack(s.config.Hostname); err != nil {
-		return &UpdateServiceError{Message: err.Error(), Reason: "stack not found"}
+		return &UpdateServiceError{Message: err.Error(), Reason: "stack not found", Err: err}
+	}
+
+	if err := s.tracker.Step("update.read-retention", s.__updateReadRetention); err != nil {
+		return err
 	}
 
-	if err := s.__deployMakeDirHierarchy(); err != nil {
+	s.bundle = newBundle(s.libDir())
+
+	if err := s.tracker.Step("update.make-dir-hierarchy", s.__deployMakeDirHierarchy); err != nil {
+		return convertError(err, &UpdateServiceError{})
+	}
+
+	if err := s.tracker.Step("update.copy-loki-config", s.__deployCopyLokiConfig); err != nil {
+		return convertError(err, &UpdateServiceError{})
+	}
+
+	if err := s.tracker.Step("update.copy-traefik-http-config", s.__deployCopyTraefikHttpConfig); err != nil {
+		return convertError(err, &UpdateServiceError{})
+	}
+
+	if err := s.tracker.Step("update.copy-traefik-http-tls-config", s.__deployCopyTraefikHttpTlsConfig); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__deploySetDirHierarchyPermission(); err != nil {
+	if err := s.tracker.Step("update.copy-alloy-config", s.__deployCopyAlloyConfig); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__deployCopyLokiConfig(); err != nil {
+	if err := s.tracker.Step("update.copy-mimir-config", s.__deployCopyMimirConfig); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__deployCopyTraefikHttpConfig(); err != nil {
+	if err := s.tracker.Step("update.copy-grafana-dashboards", s.__deployCopyGrafanaDashboards); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__deployCopyTraefikHttpTlsConfig(); err != nil {
+	if err := s.tracker.Step("update.copy-grafana-alerts", s.__deployCopyGrafanaAlerts); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__deployCopyAlloyConfig(); err != nil {
+	if err := s.tracker.Step("update.copy-pyroscope-config", s.__deployCopyPyroscopeConfig); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__deployCopyMimirConfig(); err != nil {
+	if err := s.tracker.Step("update.pin-image-digests", s.__deployPinImageDigests); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__deployCopyGrafanaDashboards(); err != nil {
+	if err := s.tracker.Step("update.copy-compose-file", s.__deployCopyComposeFile); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__deployCopyGrafanaAlerts(); err != nil {
+	if err := s.tracker.Step("update.write-stack-manifest", s.__stackWriteManifest); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__deployCopyPyroscopeConfig(); err != nil {
+	if err := s.tracker.Step("update.load-images", s.__offlineLoadImages); err != nil {
 		return convertError(err, &UpdateServiceError{})
 	}
 
-	if err := s.__updateRecomposeDockerServices(); err != nil {
+	// Keep the previous configuration and images, so a failed update is
+	// rolled back instead of leaving the stack half-upgraded.
+	var images []string
+	if err := s.tracker.Step("update.save-rollback", func() (err error) {
+		images, err = s.__updateSaveRollback()
+		return err
+	}); err != nil {
+		return err
+	}
+	removeRollback := func() {
+		_, _ = s.target.Run(s.ctx, "sudo rm -f "+path.Join(s.libDir(), rollbackArchive))
+	}
+
+	err := s.tracker.Step("update.upload-bundle", s.__helperUploadBundle)
+	if err == nil {
+		err = s.tracker.Step("update.recompose-docker-services", s.__updateRecomposeDockerServices)
+	}
+	if err != nil {
+		rollbackErr := s.tracker.Step("update.rollback", func() error {
+			return s.__updateRollback(images)
+		})
+		if rollbackErr == nil {
+			removeRollback()
+		}
+		return s.__updateRolledBack(err, rollbackErr)
+	}
+	removeRollback()
+
+	if err := s.tracker.Step("update.prune-images", s.__updatePruneImages); err != nil {
 		return err
 	}
 
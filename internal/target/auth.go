@@ -0,0 +1,196 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/melbahja/goph"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/term"
+)
+
+var askSecret = ask
+
+// askMutex keeps prompts of targets used in parallel apart.
+var askMutex sync.Mutex
+
+type identitySigner struct {
+	file   string
+	raw    []byte
+	public ssh.PublicKey
+	once   sync.Once
+	signer ssh.Signer
+	err    error
+}
+
+func (s *identitySigner) PublicKey() ssh.PublicKey {
+	return s.public
+}
+
+func (s *identitySigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	signer, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.Sign(rand, data)
+}
+
+func (s *identitySigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	signer, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	as, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("key %s does not support algorithm %s", s.file, algorithm)
+	}
+
+	return as.SignWithAlgorithm(rand, data, algorithm)
+}
+
+func (s *identitySigner) load() (ssh.Signer, error) {
+	s.once.Do(func() {
+		passphrase, err := askSecret(fmt.Sprintf("Enter passphrase for key '%s': ", s.file))
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.signer, s.err = ssh.ParsePrivateKeyWithPassphrase(s.raw, []byte(passphrase))
+	})
+
+	return s.signer, s.err
+}
+
+func loadIdentity(file string) (ssh.Signer, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(raw)
+	if err == nil {
+		return signer, nil
+	}
+
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return nil, fmt.Errorf("failed to parse key %s: %w", file, err)
+	}
+
+	s := &identitySigner{file: file, raw: raw, public: missing.PublicKey}
+	if s.public == nil {
+		if pub, err := os.ReadFile(file + ".pub"); err == nil {
+			s.public, _, _, _, _ = ssh.ParseAuthorizedKey(pub)
+		}
+	}
+	if s.public == nil {
+		signer, err := s.load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key %s: %w", file, err)
+		}
+		s.public = signer.PublicKey()
+	}
+
+	return s, nil
+}
+
+func agentSigners() []ssh.Signer {
+	if !goph.HasAgent() {
+		return nil
+	}
+
+	conn, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
+	if err != nil {
+		return nil
+	}
+
+	signers, err := agent.NewClient(conn).Signers()
+	if err != nil {
+		return nil
+	}
+
+	return signers
+}
+
+func authorize(hc *hostConfig, explicit string) (goph.Auth, error) {
+	signers := agentSigners()
+
+	for _, file := range hc.IdentityFiles {
+		signer, err := loadIdentity(file)
+		if err != nil {
+			if file == explicit {
+				return nil, err
+			}
+			continue
+		}
+
+		known := false
+		for _, s := range signers {
+			if bytes.Equal(s.PublicKey().Marshal(), signer.PublicKey().Marshal()) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			signers = append(signers, signer)
+		}
+	}
+
+	var auth goph.Auth
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+
+	var once sync.Once
+	var password string
+	var passwordErr error
+	askPassword := func() (string, error) {
+		once.Do(func() {
+			password, passwordErr = askSecret(fmt.Sprintf("Enter SSH password for %s@%s: ", hc.User, hc.Hostname))
+		})
+		return password, passwordErr
+	}
+
+	auth = append(auth,
+		ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range questions {
+				pass, err := askPassword()
+				if err != nil {
+					return nil, err
+				}
+				answers[i] = pass
+			}
+			return answers, nil
+		}),
+		ssh.PasswordCallback(askPassword),
+	)
+
+	return auth, nil
+}
+
+func ask(prompt string) (string, error) {
+	askMutex.Lock()
+	defer askMutex.Unlock()
+
+	fmt.Print(prompt)
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	if err != nil {
+		return "", err
+	}
+	fmt.Println("")
+	return strings.TrimSpace(string(pass)), nil
+}
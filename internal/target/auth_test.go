@@ -0,0 +1,85 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func writeIdentity(t *testing.T, passphrase string) (string, ssh.PublicKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err, "generate key")
+
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(priv, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	}
+	assert.NoError(t, err, "marshal key")
+
+	file := filepath.Join(t.TempDir(), "id_ed25519")
+	err = os.WriteFile(file, pem.EncodeToMemory(block), 0600)
+	assert.NoError(t, err, "write key")
+
+	key, err := ssh.NewPublicKey(pub)
+	assert.NoError(t, err, "convert public key")
+
+	return file, key
+}
+
+func Test_LoadIdentityReadsUnencryptedKey(t *testing.T) {
+	file, key := writeIdentity(t, "")
+
+	signer, err := loadIdentity(file)
+	assert.NoError(t, err, "load identity")
+	assert.Equal(t, key.Marshal(), signer.PublicKey().Marshal(), "public key")
+}
+
+func Test_LoadIdentityAsksPassphraseOnFirstUse(t *testing.T) {
+	orig := askSecret
+	defer func() { askSecret = orig }()
+
+	asked := 0
+	askSecret = func(prompt string) (string, error) {
+		asked++
+		return "secret", nil
+	}
+
+	file, key := writeIdentity(t, "secret")
+
+	signer, err := loadIdentity(file)
+	assert.NoError(t, err, "load identity")
+	assert.Equal(t, key.Marshal(), signer.PublicKey().Marshal(), "public key")
+	assert.Equal(t, 0, asked, "passphrase not asked before signing")
+
+	for range 2 {
+		sig, err := signer.Sign(rand.Reader, []byte("data"))
+		assert.NoError(t, err, "sign data")
+		assert.NoError(t, key.Verify([]byte("data"), sig), "verify signature")
+	}
+	assert.Equal(t, 1, asked, "passphrase asked once")
+}
+
+func Test_AuthorizeFailsIfExplicitIdentityIsMissing(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	hc := &hostConfig{Hostname: "example.com", User: "root", IdentityFiles: []string{missing}}
+
+	_, err := authorize(hc, missing)
+	assert.Error(t, err, "explicit identity must exist")
+
+	auth, err := authorize(hc, "")
+	assert.NoError(t, err, "default identity may be missing")
+	assert.NotEmpty(t, auth, "password fallback")
+}
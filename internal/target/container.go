@@ -0,0 +1,183 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var dockerCommand = "docker"
+
+type container struct {
+	Name       string
+	User       string
+	format     Format
+	out        io.Writer
+	dryRun     bool
+	cmdTimeout time.Duration
+	escalate   *escalator
+}
+
+func (c *container) Run(ctx context.Context, cmd string) ([]byte, error) {
+	cmd, count := c.escalate.command(cmd)
+	FprintProgress(c.out, fmt.Sprintf("Running '%s' as %s@%s", cmd, c.user(), c.Name), c.format)
+	if c.dryRun {
+		return nil, nil
+	}
+
+	return c.run(ctx, cmd, count, c.format)
+}
+
+func (c *container) RunForce(ctx context.Context, cmd string) ([]byte, error) {
+	cmd, count := c.escalate.command(cmd)
+	FprintProgress(c.out, fmt.Sprintf("Running '%s' as %s@%s", cmd, c.user(), c.Name), c.format)
+
+	return c.run(ctx, cmd, count, FormatQuiet)
+}
+
+func (c *container) run(ctx context.Context, cmd string, count int, format Format) ([]byte, error) {
+	stdin, err := c.escalate.input(count)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cmdTimeout)
+	defer cancel()
+
+	out := newOutput(cmd, format, c.out)
+	dc := c.command(ctx, c.User, stdin, "sh", "-c", cmd)
+	dc.Stdout = out.Writer("stdout")
+	dc.Stderr = out.Writer("stderr")
+	err = dc.Run()
+
+	return out.Bytes(), exitError(err)
+}
+
+func (c *container) Stream(ctx context.Context, cmd string, w io.Writer) error {
+	cmd, count := c.escalate.command(cmd)
+	FprintProgress(c.out, fmt.Sprintf("Running '%s' as %s@%s", cmd, c.user(), c.Name), c.format)
+
+	stdin, err := c.escalate.input(count)
+	if err != nil {
+		return err
+	}
+
+	dc := c.command(ctx, c.User, stdin, "sh", "-c", cmd)
+	dc.Stdout = w
+	dc.Stderr = w
+
+	return exitError(dc.Run())
+}
+
+func (c *container) Copy(ctx context.Context, src, dest, mode, owner string) ([]byte, error) {
+	FprintProgress(c.out, fmt.Sprintf("Copying from '%s' to '%s' as %s@%s", src, dest, c.user(), c.Name), c.format)
+	if c.dryRun {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cmdTimeout)
+	defer cancel()
+
+	raw, err := c.exec(ctx, "root", "mktemp", "-p", "/tmp", "-d", "finch-XXXXXX")
+	if err != nil {
+		return raw, err
+	}
+	tmpdest := strings.TrimSpace(string(raw))
+	defer func() {
+		cleanCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, _ = c.exec(cleanCtx, "root", "rm", "-rf", tmpdest)
+	}()
+
+	cpCmd := []string{"cp", src, c.Name + ":" + tmpdest + "/file"}
+	if out, err := exec.CommandContext(ctx, dockerCommand, cpCmd...).CombinedOutput(); err != nil {
+		return out, err
+	}
+
+	installCmd := []string{"install", "-m", mode, tmpdest + "/file", dest}
+	if owner != "" {
+		parts := strings.SplitN(owner, ":", 2)
+		if len(parts) == 2 {
+			installCmd = []string{"install", "-m", mode, "-o", parts[0], "-g", parts[1], tmpdest + "/file", dest}
+		}
+	}
+	if out, err := c.exec(ctx, "root", installCmd...); err != nil {
+		return out, err
+	}
+
+	return nil, nil
+}
+
+func (c *container) Fetch(ctx context.Context, src, dest string) ([]byte, error) {
+	FprintProgress(c.out, fmt.Sprintf("Fetching from '%s' to '%s' as %s@%s", src, dest, c.user(), c.Name), c.format)
+	if c.dryRun {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cmdTimeout)
+	defer cancel()
+
+	cpCmd := []string{"cp", c.Name + ":" + src, dest}
+	if out, err := exec.CommandContext(ctx, dockerCommand, cpCmd...).CombinedOutput(); err != nil {
+		return out, err
+	}
+
+	return nil, nil
+}
+
+func (c *container) exec(ctx context.Context, user string, command ...string) ([]byte, error) {
+	out, err := c.command(ctx, user, nil, command...).CombinedOutput()
+	return out, exitError(err)
+}
+
+func (c *container) command(ctx context.Context, user string, stdin io.Reader, command ...string) *exec.Cmd {
+	args := []string{"exec"}
+	if stdin != nil {
+		args = append(args, "--interactive")
+	}
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+	args = append(args, c.Name)
+	args = append(args, command...)
+
+	dc := exec.CommandContext(ctx, dockerCommand, args...)
+	dc.Stdin = stdin
+
+	return dc
+}
+
+func (c *container) user() string {
+	if c.User == "" {
+		return "default"
+	}
+
+	return c.User
+}
+
+func newContainer(host *url.URL, opts Options) (Target, error) {
+	name := host.Hostname()
+	if name == "" {
+		return nil, fmt.Errorf("invalid host URL: missing container name")
+	}
+
+	c := &container{
+		Name:       name,
+		User:       host.User.Username(),
+		format:     opts.Format,
+		out:        opts.Output,
+		dryRun:     opts.DryRun,
+		cmdTimeout: opts.CmdTimeout,
+	}
+	c.escalate = newEscalator(opts.Escalation, c.user(), name)
+
+	return c, nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeDocker(t *testing.T) string {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\n" +
+		"case \"$*\" in *mktemp*) echo /tmp/finch-abc123 ;; *) echo ok ;; esac\n"
+
+	file := filepath.Join(dir, "docker")
+	err := os.WriteFile(file, []byte(script), 0755)
+	assert.NoError(t, err, "write fake docker")
+
+	orig := dockerCommand
+	dockerCommand = file
+	t.Cleanup(func() { dockerCommand = orig })
+
+	return log
+}
+
+func calls(t *testing.T, log string) []string {
+	raw, err := os.ReadFile(log)
+	assert.NoError(t, err, "read calls")
+
+	return strings.Split(strings.TrimSpace(string(raw)), "\n")
+}
+
+func Test_NewReturnsContainerTargetIfSchemeIsDocker(t *testing.T) {
+	target, err := New("docker://deploy@finch-agent_1", Options{})
+
+	assert.NoError(t, err)
+	assert.IsType(t, &container{}, target, "target should be type container")
+	assert.Equal(t, "finch-agent_1", target.(*container).Name, "container name")
+	assert.Equal(t, "deploy", target.(*container).User, "container user")
+
+	_, err = New("podman://finch", Options{})
+	assert.ErrorContains(t, err, "unsupported target scheme podman", "error message")
+}
+
+func Test_ContainerRunsCommandWithDockerExec(t *testing.T) {
+	log := fakeDocker(t)
+
+	target, _ := New("docker://finch", Options{CmdTimeout: 10 * time.Second})
+	out, err := target.Run(context.Background(), "uname -sm")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok\n", string(out), "command output")
+	assert.Equal(t, []string{"exec finch sh -c uname -sm"}, calls(t, log), "docker calls")
+}
+
+func Test_ContainerStreamsCommandOutput(t *testing.T) {
+	log := fakeDocker(t)
+
+	target, _ := New("docker://finch", Options{CmdTimeout: 10 * time.Second})
+	streamer, ok := target.(Streamer)
+	assert.True(t, ok, "container target streams")
+
+	var out strings.Builder
+	err := streamer.Stream(context.Background(), "docker logs --follow finch", &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok\n", out.String(), "command output")
+	assert.Equal(t, []string{"exec finch sh -c docker logs --follow finch"}, calls(t, log), "docker calls")
+}
+
+func Test_ContainerCopiesFileWithDockerCpAndInstall(t *testing.T) {
+	log := fakeDocker(t)
+
+	target, _ := New("docker://deploy@finch", Options{CmdTimeout: 10 * time.Second})
+	_, err := target.Copy(context.Background(), "/tmp/alloy", "/usr/bin/alloy", "755", "root:root")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"exec --user root finch mktemp -p /tmp -d finch-XXXXXX",
+		"cp /tmp/alloy finch:/tmp/finch-abc123/file",
+		"exec --user root finch install -m 755 -o root -g root /tmp/finch-abc123/file /usr/bin/alloy",
+		"exec --user root finch rm -rf /tmp/finch-abc123",
+	}, calls(t, log), "docker calls")
+}
+
+func Test_ContainerFetchesFileWithDockerCp(t *testing.T) {
+	log := fakeDocker(t)
+
+	target, _ := New("docker://finch", Options{CmdTimeout: 10 * time.Second})
+	_, err := target.Fetch(context.Background(), "/etc/alloy/config.alloy", "/tmp/config.alloy")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cp finch:/etc/alloy/config.alloy /tmp/config.alloy"}, calls(t, log), "docker calls")
+}
+
+func Test_ContainerDoesNotCallDockerOnDryRun(t *testing.T) {
+	log := fakeDocker(t)
+
+	target, _ := New("docker://finch", Options{DryRun: true, CmdTimeout: 10 * time.Second})
+	_, err := target.Run(context.Background(), "uname -sm")
+	assert.NoError(t, err)
+	_, err = target.Copy(context.Background(), "/tmp/alloy", "/usr/bin/alloy", "755", "")
+	assert.NoError(t, err)
+	_, err = target.Fetch(context.Background(), "/etc/alloy/config.alloy", "/tmp/config.alloy")
+	assert.NoError(t, err)
+
+	_, err = os.Stat(log)
+	assert.ErrorIs(t, err, os.ErrNotExist, "docker not called")
+}
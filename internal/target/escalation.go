@@ -0,0 +1,128 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type Escalation int64
+
+const (
+	EscalationSudo         Escalation = 0
+	EscalationSudoPassword Escalation = 1
+	EscalationDoas         Escalation = 2
+	EscalationNone         Escalation = 3
+)
+
+// sudoPasswordFeed reads the password from stdin and hands it to sudo alone,
+// the escalated commands use the cached credentials and never see stdin.
+const sudoPasswordFeed = `IFS= read -r p && printf '%s\n' "$p" | sudo -S -p '' -v && unset p && `
+
+// Commands are written with a plain sudo prefix, the target rewrites every
+// sudo in command position, including after a reserved word of a compound
+// command, according to the configured escalation.
+var sudoCommand = regexp.MustCompile(`(^|[;&|(]|\b(?:if|then|elif|else|do|while|until)\b)(\s*)sudo(\s+|$)`)
+
+// Tool returns the binary used to escalate privileges, it is empty if the
+// user is expected to be root already.
+func (e Escalation) Tool() string {
+	switch e {
+	case EscalationDoas:
+		return "doas"
+	case EscalationNone:
+		return ""
+	default:
+		return "sudo"
+	}
+}
+
+// PermissionCheck returns a command that succeeds if the user may escalate
+// privileges without interaction.
+func (e Escalation) PermissionCheck() string {
+	switch e {
+	case EscalationSudoPassword:
+		return "sudo -v"
+	case EscalationNone:
+		return `test "$(id -u)" -eq 0`
+	default:
+		return "sudo -n true"
+	}
+}
+
+type escalator struct {
+	escalation Escalation
+	prompt     string
+	once       sync.Once
+	password   string
+	err        error
+}
+
+func newEscalator(escalation Escalation, user, host string) *escalator {
+	return &escalator{
+		escalation: escalation,
+		prompt:     fmt.Sprintf("Enter sudo password for %s@%s: ", user, host),
+	}
+}
+
+func (e *escalator) prefix() []string {
+	switch e.escalation {
+	case EscalationSudoPassword:
+		return []string{"sudo", "-n"}
+	case EscalationDoas:
+		return []string{"doas"}
+	case EscalationNone:
+		return nil
+	default:
+		return []string{"sudo"}
+	}
+}
+
+func (e *escalator) command(cmd string) (string, int) {
+	count := len(sudoCommand.FindAllStringIndex(cmd, -1))
+	if count == 0 || e.escalation == EscalationSudo {
+		return cmd, count
+	}
+
+	replacement := "${1}${2}"
+	if prefix := e.prefix(); prefix != nil {
+		replacement += strings.Join(prefix, " ") + " "
+	}
+
+	cmd = sudoCommand.ReplaceAllString(cmd, replacement)
+	if e.escalation == EscalationSudoPassword {
+		cmd = sudoPasswordFeed + "{ " + strings.TrimRight(cmd, "; ") + "; } </dev/null"
+	}
+
+	return cmd, count
+}
+
+func (e *escalator) args(args ...string) []string {
+	prefix := e.prefix()
+	if e.escalation == EscalationSudoPassword {
+		prefix = []string{"sudo", "-S", "-p", ""}
+	}
+
+	return append(prefix, args...)
+}
+
+func (e *escalator) input(count int) (io.Reader, error) {
+	if e.escalation != EscalationSudoPassword || count == 0 {
+		return nil, nil
+	}
+
+	e.once.Do(func() {
+		e.password, e.err = askSecret(e.prompt)
+	})
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return strings.NewReader(e.password + "\n"), nil
+}
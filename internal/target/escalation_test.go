@@ -0,0 +1,125 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EscalatorRewritesSudoInCommandPosition(t *testing.T) {
+	cmd := "sudo mkdir -p /var/lib/finch && sudo tar -xpf b.tar; command -v sudo | sudo tee x"
+
+	tests := map[Escalation]string{
+		EscalationSudo:         cmd,
+		EscalationSudoPassword: sudoPasswordFeed + "{ sudo -n mkdir -p /var/lib/finch && sudo -n tar -xpf b.tar; command -v sudo | sudo -n tee x; } </dev/null",
+		EscalationDoas:         "doas mkdir -p /var/lib/finch && doas tar -xpf b.tar; command -v sudo | doas tee x",
+		EscalationNone:         "mkdir -p /var/lib/finch && tar -xpf b.tar; command -v sudo | tee x",
+	}
+
+	for escalation, wanted := range tests {
+		rewritten, count := newEscalator(escalation, "root", "localhost").command(cmd)
+		assert.Equal(t, wanted, rewritten, "rewritten command")
+		assert.Equal(t, 3, count, "escalated commands")
+	}
+}
+
+func Test_EscalatorRewritesSudoInCompoundCommands(t *testing.T) {
+	cmd := "if command -v apt-get >/dev/null; then sudo apt-get install -y docker.io; " +
+		"elif sudo test -x /usr/bin/dnf; then sudo dnf install -y moby-engine; else sudo true; fi; " +
+		"for f in a b; do sudo rm $f; done; echo pseudo sudo"
+
+	rewritten, count := newEscalator(EscalationDoas, "root", "localhost").command(cmd)
+	assert.Equal(t, "if command -v apt-get >/dev/null; then doas apt-get install -y docker.io; "+
+		"elif doas test -x /usr/bin/dnf; then doas dnf install -y moby-engine; else doas true; fi; "+
+		"for f in a b; do doas rm $f; done; echo pseudo sudo", rewritten, "rewritten command")
+	assert.Equal(t, 5, count, "escalated commands")
+}
+
+func Test_EscalatorAsksPasswordOnce(t *testing.T) {
+	orig := askSecret
+	defer func() { askSecret = orig }()
+
+	asked := 0
+	askSecret = func(prompt string) (string, error) {
+		asked++
+		assert.Equal(t, "Enter sudo password for deploy@finch.example.com: ", prompt, "prompt")
+		return "secret", nil
+	}
+
+	e := newEscalator(EscalationSudoPassword, "deploy", "finch.example.com")
+
+	stdin, err := e.input(0)
+	assert.NoError(t, err)
+	assert.Nil(t, stdin, "no input without escalated command")
+	assert.Equal(t, 0, asked, "password not asked without escalated command")
+
+	for range 2 {
+		stdin, err = e.input(2)
+		assert.NoError(t, err)
+		raw, _ := io.ReadAll(stdin)
+		assert.Equal(t, "secret\n", string(raw), "password once per command")
+	}
+	assert.Equal(t, 1, asked, "password asked once")
+
+	stdin, err = newEscalator(EscalationSudo, "deploy", "finch.example.com").input(2)
+	assert.NoError(t, err)
+	assert.Nil(t, stdin, "no input for passwordless sudo")
+}
+
+func Test_LocalPassesPasswordToSudo(t *testing.T) {
+	orig := askSecret
+	defer func() { askSecret = orig }()
+	askSecret = func(prompt string) (string, error) {
+		return "secret", nil
+	}
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"case \"$1\" in -n) shift; exec \"$@\";; esac\n" +
+		"[ \"$1 $2 $3\" = \"-S -p \" ] || exit 2\nshift 3\nread -r password\n[ \"$password\" = secret ] || exit 1\n" +
+		"[ \"$1\" = -v ] && exit 0\nexec \"$@\"\n"
+	err := os.WriteFile(filepath.Join(dir, "sudo"), []byte(script), 0755)
+	assert.NoError(t, err, "write fake sudo")
+	t.Setenv("PATH", dir+":"+os.Getenv("PATH"))
+
+	target, err := New("localhost", Options{CmdTimeout: 10 * time.Second, Escalation: EscalationSudoPassword})
+	assert.NoError(t, err, "create local target")
+
+	out, err := target.Run(context.Background(), "sudo echo hello")
+	assert.NoError(t, err, "run escalated command")
+	assert.Equal(t, "hello\n", string(out), "command output")
+
+	out, err = target.Run(context.Background(), "sudo cat; sudo cat")
+	assert.NoError(t, err, "run escalated commands reading stdin")
+	assert.Empty(t, string(out), "password not passed to the commands")
+
+	src := filepath.Join(dir, "src")
+	err = os.WriteFile(src, []byte("content"), 0600)
+	assert.NoError(t, err, "write source")
+
+	_, err = target.Copy(context.Background(), src, filepath.Join(dir, "dest"), "644", "")
+	assert.NoError(t, err, "copy with escalation")
+}
+
+func Test_LocalRunsWithoutEscalationIfNone(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "sudo"), []byte("#!/bin/sh\nexit 1\n"), 0755)
+	assert.NoError(t, err, "write failing sudo")
+	t.Setenv("PATH", dir+":"+os.Getenv("PATH"))
+
+	target, err := New("localhost", Options{CmdTimeout: 10 * time.Second, Escalation: EscalationNone})
+	assert.NoError(t, err, "create local target")
+
+	out, err := target.Run(context.Background(), "sudo echo hello")
+	assert.NoError(t, err, "run command")
+	assert.Equal(t, "hello\n", string(out), "command output")
+}
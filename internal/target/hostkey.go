@@ -0,0 +1,187 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var knownHostsMutex sync.Mutex
+
+type hostKeyVerifier struct {
+	policy HostKeyPolicy
+	file   string
+	known  ssh.HostKeyCallback
+}
+
+// KnownHostsFile returns the path of the user's OpenSSH known_hosts file.
+func KnownHostsFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".ssh", "known_hosts"), nil
+}
+
+func newHostKeyVerifier(policy HostKeyPolicy, file string) (*hostKeyVerifier, error) {
+	v := &hostKeyVerifier{
+		policy: policy,
+		file:   file,
+	}
+	if policy == HostKeyInsecure {
+		return v, nil
+	}
+
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		if policy == HostKeyStrict {
+			return v, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create known hosts directory: %w", err)
+		}
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create known hosts file: %w", err)
+		}
+		_ = f.Close()
+	}
+
+	known, err := knownhosts.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts file: %w", err)
+	}
+	v.known = known
+
+	return v, nil
+}
+
+func (v *hostKeyVerifier) Callback() ssh.HostKeyCallback {
+	if v.policy == HostKeyInsecure {
+		return ssh.InsecureIgnoreHostKey()
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := v.check(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		if len(keyErr.Want) > 0 {
+			var expected []string
+			for _, want := range keyErr.Want {
+				expected = append(expected, fmt.Sprintf("%s %s (%s:%d)",
+					want.Key.Type(), ssh.FingerprintSHA256(want.Key), want.Filename, want.Line))
+			}
+			return fmt.Errorf("host key mismatch for %s: got %s %s, expected %s",
+				hostname, key.Type(), ssh.FingerprintSHA256(key), strings.Join(expected, ", "))
+		}
+
+		if v.policy == HostKeyStrict {
+			return fmt.Errorf("unknown host key for %s: %s %s is not in %s",
+				hostname, key.Type(), ssh.FingerprintSHA256(key), v.file)
+		}
+
+		if err := v.add(hostname, remote, key); err != nil {
+			return err
+		}
+
+		// Reload, so a reconnect accepts the recorded key.
+		known, err := knownhosts.New(v.file)
+		if err != nil {
+			return fmt.Errorf("failed to read known hosts file: %w", err)
+		}
+		v.known = known
+
+		return nil
+	}
+}
+
+// Algorithms returns the host key algorithms matching the keys already known
+// for the address, so that the server presents a key we are able to verify.
+func (v *hostKeyVerifier) Algorithms(addr string) []string {
+	if v.known == nil {
+		return nil
+	}
+
+	err := v.known(addr, &net.TCPAddr{IP: net.IPv4zero}, probeKey{})
+
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	for _, want := range keyErr.Want {
+		types := []string{want.Key.Type()}
+		if want.Key.Type() == ssh.KeyAlgoRSA {
+			types = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+		}
+		for _, t := range types {
+			if !slices.Contains(algorithms, t) {
+				algorithms = append(algorithms, t)
+			}
+		}
+	}
+
+	return algorithms
+}
+
+func (v *hostKeyVerifier) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	if v.known == nil {
+		return &knownhosts.KeyError{}
+	}
+
+	return v.known(hostname, remote, key)
+}
+
+func (v *hostKeyVerifier) add(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsMutex.Lock()
+	defer knownHostsMutex.Unlock()
+
+	f, err := os.OpenFile(v.file, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	addresses := []string{knownhosts.Normalize(hostname)}
+	if tcp, ok := remote.(*net.TCPAddr); ok && !tcp.IP.IsUnspecified() {
+		if addr := knownhosts.Normalize(tcp.String()); addr != addresses[0] {
+			addresses = append(addresses, addr)
+		}
+	}
+	if _, err := f.WriteString(knownhosts.Line(addresses, key) + "\n"); err != nil {
+		return fmt.Errorf("failed to record host key: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: Permanently added %s key %s for %s to %s\n",
+		key.Type(), ssh.FingerprintSHA256(key), hostname, v.file)
+
+	return nil
+}
+
+type probeKey struct{}
+
+func (probeKey) Type() string                        { return "probe" }
+func (probeKey) Marshal() []byte                     { return []byte{} }
+func (probeKey) Verify([]byte, *ssh.Signature) error { return errors.New("probe key") }
@@ -0,0 +1,123 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var remoteAddr = &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err, "generate host key")
+
+	key, err := ssh.NewPublicKey(pub)
+	assert.NoError(t, err, "convert host key")
+
+	return key
+}
+
+func Test_HostKeyAcceptNewRecordsUnknownKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".ssh", "known_hosts")
+	key := newHostKey(t)
+
+	v, err := newHostKeyVerifier(HostKeyAcceptNew, file)
+	assert.NoError(t, err, "create verifier")
+
+	err = v.Callback()("example.com:22", remoteAddr, key)
+	assert.NoError(t, err, "accept unknown host key")
+
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err, "read known hosts")
+	assert.Contains(t, string(data), "example.com,192.0.2.10 ssh-ed25519", "host key recorded")
+
+	err = v.Callback()("example.com:22", remoteAddr, key)
+	assert.NoError(t, err, "accept recorded host key on reconnect")
+
+	data, err = os.ReadFile(file)
+	assert.NoError(t, err, "read known hosts")
+	assert.Equal(t, 1, strings.Count(string(data), "ssh-ed25519"), "host key recorded once")
+
+	v, err = newHostKeyVerifier(HostKeyStrict, file)
+	assert.NoError(t, err, "create verifier")
+
+	err = v.Callback()("example.com:22", remoteAddr, key)
+	assert.NoError(t, err, "recorded host key is trusted")
+}
+
+func Test_HostKeyStrictRejectsUnknownKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	key := newHostKey(t)
+
+	v, err := newHostKeyVerifier(HostKeyStrict, file)
+	assert.NoError(t, err, "create verifier")
+
+	err = v.Callback()("example.com:22", remoteAddr, key)
+	assert.ErrorContains(t, err, "unknown host key for example.com:22", "unknown host key rejected")
+	assert.ErrorContains(t, err, ssh.FingerprintSHA256(key), "error shows fingerprint")
+
+	_, err = os.Stat(file)
+	assert.ErrorIs(t, err, os.ErrNotExist, "known hosts not created")
+}
+
+func Test_HostKeyMismatchFailsWithFingerprint(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	known := newHostKey(t)
+	presented := newHostKey(t)
+
+	line := knownhosts.Line([]string{knownhosts.HashHostname("example.com")}, known)
+	err := os.WriteFile(file, []byte(line+"\n"), 0600)
+	assert.NoError(t, err, "write known hosts")
+
+	for _, policy := range []HostKeyPolicy{HostKeyStrict, HostKeyAcceptNew} {
+		v, err := newHostKeyVerifier(policy, file)
+		assert.NoError(t, err, "create verifier")
+
+		err = v.Callback()("example.com:22", remoteAddr, known)
+		assert.NoError(t, err, "hashed entry matches")
+
+		err = v.Callback()("example.com:22", remoteAddr, presented)
+		assert.ErrorContains(t, err, "host key mismatch for example.com:22", "mismatch rejected")
+		assert.ErrorContains(t, err, ssh.FingerprintSHA256(presented), "error shows presented fingerprint")
+		assert.ErrorContains(t, err, ssh.FingerprintSHA256(known), "error shows known fingerprint")
+	}
+}
+
+func Test_HostKeyInsecureAcceptsAnyKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "known_hosts")
+
+	v, err := newHostKeyVerifier(HostKeyInsecure, file)
+	assert.NoError(t, err, "create verifier")
+
+	err = v.Callback()("example.com:22", remoteAddr, newHostKey(t))
+	assert.NoError(t, err, "any host key accepted")
+
+	_, err = os.Stat(file)
+	assert.ErrorIs(t, err, os.ErrNotExist, "known hosts not created")
+}
+
+func Test_HostKeyAlgorithmsPreferKnownKeyTypes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{"example.com"}, newHostKey(t))
+	err := os.WriteFile(file, []byte(line+"\n"), 0600)
+	assert.NoError(t, err, "write known hosts")
+
+	v, err := newHostKeyVerifier(HostKeyStrict, file)
+	assert.NoError(t, err, "create verifier")
+
+	assert.Equal(t, []string{ssh.KeyAlgoED25519}, v.Algorithms("example.com:22"), "known key type")
+	assert.Empty(t, v.Algorithms("unknown.example.com:22"), "no preference for unknown host")
+}
@@ -7,6 +7,7 @@ package target
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/url"
 	"os/exec"
 	"strings"
@@ -17,32 +18,67 @@ type local struct {
 	Host       string
 	User       string
 	format     Format
+	out        io.Writer
 	dryRun     bool
 	cmdTimeout time.Duration
+	escalate   *escalator
 }
 
 func (l *local) Run(ctx context.Context, cmd string) ([]byte, error) {
-	PrintProgress(fmt.Sprintf("Running '%s' as %s@%s", cmd, l.User, l.Host), l.format)
+	cmd, count := l.escalate.command(cmd)
+	FprintProgress(l.out, fmt.Sprintf("Running '%s' as %s@%s", cmd, l.User, l.Host), l.format)
 	if l.dryRun {
 		return nil, nil
 	}
 
+	return l.run(ctx, cmd, count, l.format)
+}
+
+func (l *local) RunForce(ctx context.Context, cmd string) ([]byte, error) {
+	cmd, count := l.escalate.command(cmd)
+	FprintProgress(l.out, fmt.Sprintf("Running '%s' as %s@%s", cmd, l.User, l.Host), l.format)
+
+	return l.run(ctx, cmd, count, FormatQuiet)
+}
+
+func (l *local) run(ctx context.Context, cmd string, count int, format Format) ([]byte, error) {
+	stdin, err := l.escalate.input(count)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, l.cmdTimeout)
 	defer cancel()
 
-	return exec.CommandContext(ctx, "sh", "-c", cmd).CombinedOutput()
+	out := newOutput(cmd, format, l.out)
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Stdin = stdin
+	c.Stdout = out.Writer("stdout")
+	c.Stderr = out.Writer("stderr")
+	err = c.Run()
+
+	return out.Bytes(), exitError(err)
 }
 
-func (l *local) RunForce(ctx context.Context, cmd string) ([]byte, error) {
-	dryRun := l.dryRun
-	l.dryRun = false
-	defer func() { l.dryRun = dryRun }()
+func (l *local) Stream(ctx context.Context, cmd string, w io.Writer) error {
+	cmd, count := l.escalate.command(cmd)
+	FprintProgress(l.out, fmt.Sprintf("Running '%s' as %s@%s", cmd, l.User, l.Host), l.format)
+
+	stdin, err := l.escalate.input(count)
+	if err != nil {
+		return err
+	}
+
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Stdin = stdin
+	c.Stdout = w
+	c.Stderr = w
 
-	return l.Run(ctx, cmd)
+	return exitError(c.Run())
 }
 
 func (l *local) Copy(ctx context.Context, src, dest, mode, owner string) ([]byte, error) {
-	PrintProgress(fmt.Sprintf("Copying from '%s' to '%s' as %s@%s", src, dest, l.User, l.Host), l.format)
+	FprintProgress(l.out, fmt.Sprintf("Copying from '%s' to '%s' as %s@%s", src, dest, l.User, l.Host), l.format)
 	if l.dryRun {
 		return nil, nil
 	}
@@ -50,26 +86,63 @@ func (l *local) Copy(ctx context.Context, src, dest, mode, owner string) ([]byte
 	ctx, cancel := context.WithTimeout(ctx, l.cmdTimeout)
 	defer cancel()
 
-	installCmd := []string{"sudo", "install", "-m", mode, src, dest}
+	installCmd := l.escalate.args("install", "-m", mode, src, dest)
 	if owner != "" {
 		parts := strings.SplitN(owner, ":", 2)
 		if len(parts) == 2 {
-			installCmd = []string{"sudo", "install", "-m", mode, "-o", parts[0], "-g", parts[1], src, dest}
+			installCmd = l.escalate.args("install", "-m", mode, "-o", parts[0], "-g", parts[1], src, dest)
 		}
 	}
-	if out, err := exec.CommandContext(ctx, installCmd[0], installCmd[1:]...).CombinedOutput(); err != nil {
+	if out, err := l.exec(ctx, installCmd); err != nil {
 		return out, err
 	}
 
 	return nil, nil
 }
 
+func (l *local) Fetch(ctx context.Context, src, dest string) ([]byte, error) {
+	FprintProgress(l.out, fmt.Sprintf("Fetching from '%s' to '%s' as %s@%s", src, dest, l.User, l.Host), l.format)
+	if l.dryRun {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.cmdTimeout)
+	defer cancel()
+
+	installCmd := l.escalate.args("install", "-m", "600", "-o", l.User, src, dest)
+	if out, err := l.exec(ctx, installCmd); err != nil {
+		return out, err
+	}
+
+	return nil, nil
+}
+
+func (l *local) exec(ctx context.Context, command []string) ([]byte, error) {
+	stdin, err := l.escalate.input(1)
+	if err != nil {
+		return nil, err
+	}
+
+	c := exec.CommandContext(ctx, command[0], command[1:]...)
+	c.Stdin = stdin
+
+	out, err := c.CombinedOutput()
+	return out, exitError(err)
+}
+
 func newLocal(host *url.URL, opts Options) (Target, error) {
+	username := host.User.Username()
+	if username == "" {
+		username = localUsername()
+	}
+
 	return &local{
 		Host:       host.Hostname(),
-		User:       host.User.Username(),
+		User:       username,
 		format:     opts.Format,
+		out:        opts.Output,
 		dryRun:     opts.DryRun,
 		cmdTimeout: opts.CmdTimeout,
+		escalate:   newEscalator(opts.Escalation, username, host.Hostname()),
 	}, nil
 }
@@ -0,0 +1,105 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// output collects the combined output of a command while streaming it line
+// by line in documentation and JSON format.
+type output struct {
+	command string
+	format  Format
+	w       io.Writer
+	mutex   sync.Mutex
+	buf     bytes.Buffer
+	pending map[string]*bytes.Buffer
+}
+
+type outputWriter struct {
+	output *output
+	stream string
+}
+
+func newOutput(command string, format Format, w io.Writer) *output {
+	return &output{
+		command: command,
+		format:  format,
+		w:       stdout(w),
+		pending: map[string]*bytes.Buffer{},
+	}
+}
+
+func (o *output) Writer(stream string) io.Writer {
+	o.pending[stream] = &bytes.Buffer{}
+	return &outputWriter{output: o, stream: stream}
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	o := w.output
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.buf.Write(p)
+
+	pending := o.pending[w.stream]
+	pending.Write(p)
+	for {
+		i := bytes.IndexByte(pending.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := pending.Next(i + 1)
+		printOutput(o.w, o.command, w.stream, string(line[:i]), o.format)
+	}
+
+	return len(p), nil
+}
+
+func (o *output) Bytes() []byte {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for _, stream := range []string{"stdout", "stderr"} {
+		if pending, ok := o.pending[stream]; ok && pending.Len() > 0 {
+			printOutput(o.w, o.command, stream, pending.String(), o.format)
+			pending.Reset()
+		}
+	}
+
+	return o.buf.Bytes()
+}
+
+func printOutput(w io.Writer, command, stream, line string, format Format) {
+	line = strings.TrimRight(line, "\r")
+
+	switch format {
+	case FormatDocumentation:
+		_, _ = fmt.Fprintln(w, "  | "+line)
+	case FormatJSON:
+		data := map[string]string{
+			"timestamp": time.Now().Format(time.RFC3339),
+			"command":   command,
+			"stream":    stream,
+			"output":    line,
+		}
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+			return
+		}
+		_, _ = fmt.Fprintln(w, string(jsonData))
+	default:
+		// Do nothing
+	}
+}
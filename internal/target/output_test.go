@@ -0,0 +1,68 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OutputStreamsLinesAndKeepsBuffer(t *testing.T) {
+	var buffered []byte
+	printed := capture(func(_ string, format Format) {
+		o := newOutput("make", format, nil)
+		stdout := o.Writer("stdout")
+		stderr := o.Writer("stderr")
+
+		_, _ = stdout.Write([]byte("build"))
+		_, _ = stderr.Write([]byte("warning\n"))
+		_, _ = stdout.Write([]byte("ing\ndone"))
+		buffered = o.Bytes()
+	}, "", FormatDocumentation)
+
+	assert.Equal(t, "  | warning\n  | building\n  | done\n", printed, "streamed lines")
+	assert.Equal(t, "buildwarning\ning\ndone", string(buffered), "buffered output")
+}
+
+func Test_OutputStreamsJSONPerLine(t *testing.T) {
+	printed := capture(func(_ string, format Format) {
+		o := newOutput("make", format, nil)
+		_, _ = o.Writer("stderr").Write([]byte("oops\n"))
+	}, "", FormatJSON)
+
+	var line map[string]string
+	err := json.Unmarshal([]byte(printed), &line)
+	assert.NoError(t, err, "unmarshal json output")
+	assert.Equal(t, "make", line["command"], "command")
+	assert.Equal(t, "stderr", line["stream"], "stream")
+	assert.Equal(t, "oops", line["output"], "output")
+	assert.NotEmpty(t, line["timestamp"], "timestamp")
+}
+
+func Test_LocalRunStreamsOutputButRunForceDoesNot(t *testing.T) {
+	target, err := New("localhost", Options{Format: FormatDocumentation, CmdTimeout: 10 * time.Second})
+	assert.NoError(t, err, "create local target")
+
+	var out []byte
+	printed := capture(func(cmd string, _ Format) {
+		out, err = target.Run(context.Background(), cmd)
+	}, "echo hello; echo world >&2", FormatDocumentation)
+	assert.NoError(t, err, "run command")
+	assert.Equal(t, "hello\nworld\n", string(out), "combined output")
+	assert.Contains(t, printed, "  | hello\n", "streamed stdout")
+	assert.Contains(t, printed, "  | world\n", "streamed stderr")
+
+	printed = capture(func(cmd string, _ Format) {
+		out, err = target.RunForce(context.Background(), cmd)
+	}, "echo secret", FormatDocumentation)
+	assert.NoError(t, err, "run command")
+	assert.Equal(t, "secret\n", string(out), "combined output")
+	assert.False(t, strings.Contains(printed, "  | "), "output not streamed")
+}
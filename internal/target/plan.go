@@ -0,0 +1,147 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DryRunTmpDir stands in for a temporary directory created on the target,
+// because mktemp is not run in dry-run mode.
+const DryRunTmpDir = "/tmp/finchctl-dry-run"
+
+const planTmpDirVar = "FINCH_TMPDIR"
+
+type planner struct {
+	target   Target
+	file     string
+	escalate *escalator
+	mutex    sync.Mutex
+}
+
+// NewPlanner writes every call to the target into file as a POSIX shell
+// script, with copied files embedded as base64. Queries run by RunForce and
+// files fetched are read from the target while planning and noted as
+// comments, their results are part of the plan.
+func NewPlanner(t Target, file, hostUrl string, opts Options) (Target, error) {
+	escalation := opts.Escalation
+	if escalation == EscalationSudoPassword {
+		escalation = EscalationSudo
+	}
+
+	header := fmt.Sprintf("#!/bin/sh\n# Plan for %s, generated by finchctl at %s.\n"+
+		"# Checks made while planning are repeated, the script stops if one fails.\nset -eu\n\n",
+		hostUrl, time.Now().Format(time.RFC3339))
+	if err := os.WriteFile(file, []byte(header), 0755); err != nil {
+		return nil, fmt.Errorf("failed to write plan: %w", err)
+	}
+
+	return &planner{
+		target:   t,
+		file:     file,
+		escalate: newEscalator(escalation, "", ""),
+	}, nil
+}
+
+func (p *planner) Run(ctx context.Context, cmd string) ([]byte, error) {
+	out, err := p.target.Run(ctx, cmd)
+	if err != nil {
+		return out, err
+	}
+
+	line, _ := p.escalate.command(p.substitute(cmd))
+	switch {
+	case strings.HasPrefix(cmd, "mktemp "):
+		line = fmt.Sprintf("%s=$(%s)\nexport %s", planTmpDirVar, line, planTmpDirVar)
+	case strings.ContainsAny(cmd, ";&\n") || strings.Contains(cmd, "exit"):
+		// Keep the command list and its exit status in its own shell.
+		line = "sh -c '" + strings.ReplaceAll(line, "'", `'\''`) + "'"
+	}
+
+	return out, p.write(line + "\n")
+}
+
+func (p *planner) RunForce(ctx context.Context, cmd string) ([]byte, error) {
+	out, err := p.target.RunForce(ctx, cmd)
+	if werr := p.write(fmt.Sprintf("# Queried while planning: %s\n", cmd)); werr != nil {
+		return out, werr
+	}
+
+	return out, err
+}
+
+func (p *planner) Copy(ctx context.Context, src, dest, mode, owner string) ([]byte, error) {
+	out, err := p.target.Copy(ctx, src, dest, mode, owner)
+	if err != nil {
+		return out, err
+	}
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed %s into plan: %w", src, err)
+	}
+
+	install := fmt.Sprintf("sudo install -D -m %s \"$finch_asset\" %s", mode, p.substitute(dest))
+	if parts := strings.SplitN(owner, ":", 2); len(parts) == 2 {
+		install = fmt.Sprintf("sudo install -D -m %s -o %s -g %s \"$finch_asset\" %s", mode, parts[0], parts[1], p.substitute(dest))
+	}
+	install, _ = p.escalate.command(install)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "finch_asset=$(mktemp)\nbase64 -d > \"$finch_asset\" <<'FINCH_ASSET'\n")
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\n")
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(&b, "%s\nFINCH_ASSET\n%s\nrm -f \"$finch_asset\"\n", encoded, install)
+
+	return out, p.write(b.String())
+}
+
+func (p *planner) Fetch(ctx context.Context, src, dest string) ([]byte, error) {
+	if out, err := p.target.Fetch(ctx, src, dest); err != nil {
+		return out, err
+	}
+
+	out, err := p.target.RunForce(ctx, "sudo cat "+src)
+	if err != nil {
+		return out, err
+	}
+	if err := os.WriteFile(dest, out, 0600); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s while planning: %w", src, err)
+	}
+
+	return nil, p.write(fmt.Sprintf("# Fetched while planning: %s\n", src))
+}
+
+func (p *planner) substitute(s string) string {
+	return strings.ReplaceAll(s, DryRunTmpDir, "$"+planTmpDirVar)
+}
+
+func (p *planner) write(s string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	f, err := os.OpenFile(p.file, os.O_APPEND|os.O_WRONLY, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	if _, err := f.WriteString(s); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PlannerWritesExecutableScript(t *testing.T) {
+	dir := t.TempDir()
+	plan := filepath.Join(dir, "plan.sh")
+	ctx := context.Background()
+
+	bin := filepath.Join(dir, "bin")
+	err := os.Mkdir(bin, 0755)
+	assert.NoError(t, err, "create bin dir")
+	err = os.WriteFile(filepath.Join(bin, "sudo"), []byte("#!/bin/sh\nexec \"$@\"\n"), 0755)
+	assert.NoError(t, err, "write fake sudo")
+
+	src := filepath.Join(dir, "config.alloy")
+	err = os.WriteFile(src, []byte("logging {\n  level = \"info\"\n}\n"), 0600)
+	assert.NoError(t, err, "write asset")
+
+	target, err := New("localhost", Options{DryRun: true, PlanOut: plan, CmdTimeout: 10 * time.Second})
+	assert.NoError(t, err, "create planning target")
+
+	_, err = target.Run(ctx, "mktemp -p /tmp -d finch-XXXXXX")
+	assert.NoError(t, err, "plan mktemp")
+	_, err = target.Copy(ctx, src, DryRunTmpDir+"/config.alloy", "600", "")
+	assert.NoError(t, err, "plan copy")
+	_, err = target.RunForce(ctx, "uname -sm")
+	assert.NoError(t, err, "plan query")
+	result := filepath.Join(dir, "result")
+	_, err = target.Run(ctx, "sudo cp "+DryRunTmpDir+"/config.alloy "+result+"; rc=$?; rm -rf "+DryRunTmpDir+"; exit $rc")
+	assert.NoError(t, err, "plan command list")
+	_, err = target.Run(ctx, "touch "+result+".done")
+	assert.NoError(t, err, "plan command")
+
+	script, err := os.ReadFile(plan)
+	assert.NoError(t, err, "read plan")
+	assert.Contains(t, string(script), "# Queried while planning: uname -sm\n", "query noted")
+
+	_, err = os.Stat(result)
+	assert.ErrorIs(t, err, os.ErrNotExist, "nothing run while planning")
+
+	c := exec.Command("sh", plan)
+	c.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+	out, err := c.CombinedOutput()
+	assert.NoError(t, err, "run plan: %s", out)
+
+	content, err := os.ReadFile(result)
+	assert.NoError(t, err, "read result")
+	assert.Equal(t, "logging {\n  level = \"info\"\n}\n", string(content), "embedded asset")
+	assert.FileExists(t, result+".done", "commands after command list run")
+}
@@ -0,0 +1,112 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+type call struct {
+	Method  string `json:"method"`
+	Command string `json:"command,omitempty"`
+	Match   string `json:"match,omitempty"`
+	Src     string `json:"src,omitempty"`
+	Dest    string `json:"dest,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	Content string `json:"content,omitempty"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Status  int    `json:"exit_status,omitempty"`
+}
+
+type recorder struct {
+	target Target
+	file   string
+	mutex  sync.Mutex
+}
+
+func NewRecorder(t Target, file string) Target {
+	return &recorder{target: t, file: file}
+}
+
+func (r *recorder) Run(ctx context.Context, cmd string) ([]byte, error) {
+	out, err := r.target.Run(ctx, cmd)
+	return out, r.record(call{Method: "run", Command: cmd}, out, err)
+}
+
+func (r *recorder) RunForce(ctx context.Context, cmd string) ([]byte, error) {
+	out, err := r.target.RunForce(ctx, cmd)
+	return out, r.record(call{Method: "run", Command: cmd}, out, err)
+}
+
+func (r *recorder) Copy(ctx context.Context, src, dest, mode, owner string) ([]byte, error) {
+	out, err := r.target.Copy(ctx, src, dest, mode, owner)
+	return out, r.record(call{Method: "copy", Src: src, Dest: dest, Mode: mode, Owner: owner}, out, err)
+}
+
+func (r *recorder) Fetch(ctx context.Context, src, dest string) ([]byte, error) {
+	out, err := r.target.Fetch(ctx, src, dest)
+
+	c := call{Method: "fetch", Src: src, Dest: dest}
+	if err == nil {
+		if content, rerr := os.ReadFile(dest); rerr == nil {
+			c.Content = string(content)
+		}
+	}
+
+	return out, r.record(c, out, err)
+}
+
+func (r *recorder) Stream(ctx context.Context, cmd string, w io.Writer) error {
+	streamer, ok := r.target.(Streamer)
+	if !ok {
+		return errors.New("target does not support streaming")
+	}
+
+	var out bytes.Buffer
+	err := streamer.Stream(ctx, cmd, io.MultiWriter(w, &out))
+	return r.record(call{Method: "stream", Command: cmd}, out.Bytes(), err)
+}
+
+func (r *recorder) record(c call, out []byte, err error) error {
+	c.Output = string(out)
+	if err != nil {
+		c.Error = err.Error()
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			c.Status = exitErr.Status
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	data, merr := json.Marshal(c)
+	if merr != nil {
+		return fmt.Errorf("failed to record call: %w", merr)
+	}
+
+	f, ferr := os.OpenFile(r.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if ferr != nil {
+		return fmt.Errorf("failed to record call: %w", ferr)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	if _, werr := f.Write(append(data, '\n')); werr != nil {
+		return fmt.Errorf("failed to record call: %w", werr)
+	}
+
+	return err
+}
@@ -6,16 +6,19 @@ package target
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/melbahja/goph"
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/term"
 )
 
 type remote struct {
@@ -25,37 +28,112 @@ type remote struct {
 	auth       goph.Auth
 	client     *goph.Client
 	format     Format
+	out        io.Writer
 	dryRun     bool
 	cmdTimeout time.Duration
+	escalate   *escalator
+	policy     RetryOptions
+	dial       func() (*ssh.Client, error)
+	stale      bool
 }
 
 func (s *remote) Run(ctx context.Context, cmd string) ([]byte, error) {
-	PrintProgress(fmt.Sprintf("Running '%s' as %s@%s", cmd, s.User, s.Host), s.format)
+	cmd, count := s.escalate.command(cmd)
+	FprintProgress(s.out, fmt.Sprintf("Running '%s' as %s@%s", cmd, s.User, s.Host), s.format)
 	if s.dryRun {
 		return nil, nil
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, s.cmdTimeout)
-	defer cancel()
-
-	return s.client.RunContext(ctx, cmd)
+	return s.run(ctx, cmd, count, s.format)
 }
 
 func (s *remote) RunForce(ctx context.Context, cmd string) ([]byte, error) {
-	dryRun := s.dryRun
-	s.dryRun = false
-	defer func() { s.dryRun = dryRun }()
+	cmd, count := s.escalate.command(cmd)
+	FprintProgress(s.out, fmt.Sprintf("Running '%s' as %s@%s", cmd, s.User, s.Host), s.format)
+
+	return s.run(ctx, cmd, count, FormatQuiet)
+}
+
+func (s *remote) run(ctx context.Context, cmd string, count int, format Format) ([]byte, error) {
+	var out *output
+	err := s.retry(ctx, fmt.Sprintf("'%s'", cmd), func() (bool, error) {
+		stdin, err := s.escalate.input(count)
+		if err != nil {
+			return false, err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, s.cmdTimeout)
+		defer cancel()
+
+		// A failure to open the session is retried, the command did not run.
+		c, err := s.client.CommandContext(ctx, cmd)
+		if err != nil {
+			return ctx.Err() == nil && transient(err), err
+		}
+		defer func() {
+			_ = c.Close()
+		}()
+
+		out = newOutput(cmd, format, s.out)
+		c.Stdin = stdin
+		c.Stdout = out.Writer("stdout")
+		c.Stderr = out.Writer("stderr")
+		err = c.Run()
+
+		// The command may have run before the connection failed, it is
+		// only repeated if it is idempotent. Otherwise the next call
+		// reconnects.
+		if ctx.Err() == nil && transient(err) {
+			s.stale = true
+			return isIdempotent(ctx), err
+		}
+
+		return false, err
+	})
+	if out == nil {
+		return nil, err
+	}
 
-	return s.Run(ctx, cmd)
+	return out.Bytes(), exitError(err)
+}
+
+func (s *remote) Stream(ctx context.Context, cmd string, w io.Writer) error {
+	cmd, count := s.escalate.command(cmd)
+	FprintProgress(s.out, fmt.Sprintf("Running '%s' as %s@%s", cmd, s.User, s.Host), s.format)
+
+	if s.stale {
+		if err := s.reconnect(); err != nil {
+			return err
+		}
+	}
+
+	stdin, err := s.escalate.input(count)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.client.CommandContext(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = c.Close()
+	}()
+
+	c.Stdin = stdin
+	c.Stdout = w
+	c.Stderr = w
+
+	return exitError(c.Run())
 }
 
 func (s *remote) Copy(ctx context.Context, src, dest, mode, owner string) ([]byte, error) {
-	PrintProgress(fmt.Sprintf("Copying from '%s' to '%s' as %s@%s", src, dest, s.User, s.Host), s.format)
+	FprintProgress(s.out, fmt.Sprintf("Copying from '%s' to '%s' as %s@%s", src, dest, s.User, s.Host), s.format)
 	if s.dryRun {
 		return nil, nil
 	}
 
-	raw, err := s.Run(ctx, "mktemp -p /tmp -d finch-XXXXXX")
+	raw, err := s.Run(Idempotent(ctx), "mktemp -p /tmp -d finch-XXXXXX")
 	if err != nil {
 		return nil, err
 	}
@@ -66,22 +144,13 @@ func (s *remote) Copy(ctx context.Context, src, dest, mode, owner string) ([]byt
 		_, _ = s.Run(cleanCtx, "rm -rf "+tmpdest)
 	}()
 
-	uploadCtx, cancel := context.WithTimeout(ctx, s.cmdTimeout)
-	defer cancel()
-
-	done := make(chan error, 1)
-	go func() {
-		done <- s.client.Upload(src, tmpdest+"/file")
-	}()
-
-	select {
-	case err := <-done:
-		if err != nil {
-			return nil, err
-		}
-	case <-uploadCtx.Done():
-		_ = s.client.Close()
-		return nil, fmt.Errorf("upload timed out after %s", s.cmdTimeout)
+	err = s.retry(ctx, fmt.Sprintf("upload of '%s'", src), func() (bool, error) {
+		return s.transfer(ctx, "upload", func() error {
+			return s.client.Upload(src, tmpdest+"/file")
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	installCmd := fmt.Sprintf("sudo install -m %s %s %s", mode, tmpdest+"/file", dest)
@@ -91,7 +160,7 @@ func (s *remote) Copy(ctx context.Context, src, dest, mode, owner string) ([]byt
 			installCmd = fmt.Sprintf("sudo install -m %s -o %s -g %s %s %s", mode, parts[0], parts[1], tmpdest+"/file", dest)
 		}
 	}
-	out, err := s.Run(ctx, installCmd)
+	out, err := s.Run(Idempotent(ctx), installCmd)
 	if err != nil {
 		return out, err
 	}
@@ -99,79 +168,261 @@ func (s *remote) Copy(ctx context.Context, src, dest, mode, owner string) ([]byt
 	return nil, nil
 }
 
-func newRemote(host *url.URL, opts Options) (Target, error) {
-	auth, err := authorize()
-	if err != nil {
-		return nil, fmt.Errorf("failed to authorize: %w", err)
+func (s *remote) Fetch(ctx context.Context, src, dest string) ([]byte, error) {
+	FprintProgress(s.out, fmt.Sprintf("Fetching from '%s' to '%s' as %s@%s", src, dest, s.User, s.Host), s.format)
+	if s.dryRun {
+		return nil, nil
 	}
 
-	port, err := func() (uint, error) {
-		if host.Port() == "" {
-			return 22, nil
-		}
-		port, err := strconv.Atoi(host.Port())
-		if err != nil {
-			return 0, err
-		}
-		return uint(port), nil
-	}()
+	raw, err := s.Run(Idempotent(ctx), "mktemp -p /tmp -d finch-XXXXXX")
 	if err != nil {
 		return nil, err
 	}
+	tmpsrc := strings.TrimSpace(string(raw))
+	defer func() {
+		cleanCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, _ = s.Run(cleanCtx, "rm -rf "+tmpsrc)
+	}()
+
+	out, err := s.Run(Idempotent(ctx), fmt.Sprintf("sudo install -m 600 -o %s %s %s", s.User, src, tmpsrc+"/file"))
+	if err != nil {
+		return out, err
+	}
 
-	client, err := goph.NewConn(&goph.Config{
-		User:     host.User.Username(),
-		Addr:     host.Hostname(),
-		Port:     port,
-		Auth:     auth,
-		Callback: ssh.InsecureIgnoreHostKey(),
+	err = s.retry(ctx, fmt.Sprintf("download of '%s'", src), func() (bool, error) {
+		return s.transfer(ctx, "download", func() error {
+			return s.client.Download(tmpsrc+"/file", dest)
+		})
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &remote{
-		Host:       host.Hostname(),
-		Port:       port,
-		User:       host.User.Username(),
-		auth:       auth,
-		client:     client,
-		format:     opts.Format,
-		dryRun:     opts.DryRun,
-		cmdTimeout: opts.CmdTimeout,
-	}, nil
+	return nil, nil
 }
 
-func authorize() (goph.Auth, error) {
-	var auth goph.Auth
-	var err error
+// transfer runs an SFTP upload or download with the command timeout. A
+// timed out transfer leaves the connection in an unknown state, it is closed
+// and reestablished by the next call.
+func (s *remote) transfer(ctx context.Context, action string, f func() error) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cmdTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
 
+	select {
+	case err := <-done:
+		return transient(err), err
+	case <-ctx.Done():
+		_ = s.client.Close()
+		s.stale = true
+		return false, fmt.Errorf("%s timed out after %s", action, s.cmdTimeout)
+	}
+}
+
+// retry calls f until it succeeds, fails permanently or the retries are
+// exhausted. Transient failures mark the connection stale, it is
+// reestablished before the next attempt.
+func (s *remote) retry(ctx context.Context, action string, f func() (bool, error)) error {
+	for attempt := uint(1); ; attempt++ {
+		var err error
+		retryable := true
+		if s.stale {
+			err = s.reconnect()
+		}
+		if err == nil {
+			retryable, err = f()
+		}
+		if err == nil || !retryable || attempt > s.policy.Retries {
+			return err
+		}
+
+		s.stale = true
+		delay := s.policy.Backoff << (attempt - 1)
+		FprintProgress(s.out, fmt.Sprintf("Retrying %s as %s@%s in %s after transient failure: %s (retry %d of %d)",
+			action, s.User, s.Host, delay, err, attempt, s.policy.Retries), s.format)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (s *remote) reconnect() error {
+	if s.client.Client != nil {
+		_ = s.client.Close()
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return fmt.Errorf("failed to reconnect: %w", err)
+	}
+	s.client.Client = client
+	s.stale = false
+
+	return nil
+}
+
+// transient reports whether err is a transport failure. Commands that ran and
+// exited non-zero and failed SFTP operations are permanent.
+func transient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var exitErr *ssh.ExitError
+	var statusErr *sftp.StatusError
+	var pathErr *fs.PathError
 	switch {
+	case errors.As(err, &exitErr), errors.As(err, &statusErr), errors.As(err, &pathErr):
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+
+	return true
+}
+
+func newRemote(host *url.URL, opts Options) (Target, error) {
+	cfg, err := loadSSHConfig(sshConfigFiles()...)
+	if err != nil {
+		return nil, err
+	}
+
+	hc, err := resolveHostConfig(host, opts.SSH, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	jumps, err := resolveJumpHosts(hc, opts.SSH, cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	case goph.HasAgent():
-		auth, err = goph.UseAgent()
+	knownHosts, err := KnownHostsFile()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := newHostKeyVerifier(opts.SSH.HostKeyPolicy, knownHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	var jumpAuths []goph.Auth
+	for _, jump := range jumps {
+		auth, err := authorize(jump, opts.SSH.IdentityFile)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to authorize on jump host %s: %w", jump.Alias, err)
+		}
+		jumpAuths = append(jumpAuths, auth)
+	}
+
+	auth, err := authorize(hc, opts.SSH.IdentityFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authorize: %w", err)
+	}
+
+	dial := func() (*ssh.Client, error) {
+		var err error
+		var chain []*ssh.Client
+		closeChain := func() {
+			for i := len(chain) - 1; i >= 0; i-- {
+				_ = chain[i].Close()
+			}
 		}
 
-	default:
-		password, err := ask("Enter SSH password: ")
+		var via *ssh.Client
+		for i, jump := range jumps {
+			via, err = connect(jump, jumpAuths[i], verifier, via)
+			if err != nil {
+				closeChain()
+				return nil, fmt.Errorf("failed to connect to jump host %s: %w", jump.Alias, err)
+			}
+			chain = append(chain, via)
+		}
+
+		client, err := connect(hc, auth, verifier, via)
 		if err != nil {
+			closeChain()
 			return nil, err
 		}
-		auth = goph.KeyboardInteractive(password)
+		if len(chain) > 0 {
+			go func() {
+				_ = client.Wait()
+				closeChain()
+			}()
+		}
 
+		return client, nil
 	}
 
-	return auth, nil
+	client, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	return &remote{
+		Host: hc.Hostname,
+		Port: hc.Port,
+		User: hc.User,
+		auth: auth,
+		client: &goph.Client{
+			Client: client,
+			Config: &goph.Config{
+				User:     hc.User,
+				Addr:     hc.Hostname,
+				Port:     hc.Port,
+				Auth:     auth,
+				Timeout:  goph.DefaultTimeout,
+				Callback: verifier.Callback(),
+			},
+		},
+		format:     opts.Format,
+		out:        opts.Output,
+		dryRun:     opts.DryRun,
+		cmdTimeout: opts.CmdTimeout,
+		escalate:   newEscalator(opts.Escalation, hc.User, hc.Hostname),
+		policy:     opts.Retry,
+		dial:       dial,
+	}, nil
 }
 
-func ask(prompt string) (string, error) {
-	fmt.Print(prompt)
-	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+func connect(hc *hostConfig, auth goph.Auth, verifier *hostKeyVerifier, via *ssh.Client) (*ssh.Client, error) {
+	addr := net.JoinHostPort(hc.Hostname, strconv.Itoa(int(hc.Port)))
+	config := &ssh.ClientConfig{
+		User:              hc.User,
+		Auth:              auth,
+		Timeout:           goph.DefaultTimeout,
+		HostKeyCallback:   verifier.Callback(),
+		HostKeyAlgorithms: verifier.Algorithms(addr),
+	}
+
+	if via == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+
+	conn, err := via.Dial("tcp", addr)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	timer := time.AfterFunc(config.Timeout, func() {
+		_ = conn.Close()
+	})
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if !timer.Stop() {
+		return nil, fmt.Errorf("handshake with %s timed out after %s", addr, config.Timeout)
 	}
-	fmt.Println("")
-	return strings.TrimSpace(string(pass)), nil
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(c, chans, reqs), nil
 }
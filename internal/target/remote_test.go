@@ -0,0 +1,105 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/melbahja/goph"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func retryingRemote(retries uint) (*remote, *int) {
+	dialed := 0
+	r := &remote{
+		Host:   "finch.example.com",
+		User:   "deploy",
+		client: &goph.Client{},
+		format: FormatDocumentation,
+		policy: RetryOptions{Retries: retries},
+		dial: func() (*ssh.Client, error) {
+			dialed++
+			return nil, nil
+		},
+	}
+
+	return r, &dialed
+}
+
+func Test_TransientSeparatesTransportFromCommandFailures(t *testing.T) {
+	assert.True(t, transient(io.EOF), "connection lost")
+	assert.True(t, transient(&ssh.ExitMissingError{}), "session closed without exit status")
+	assert.False(t, transient(&ssh.ExitError{}), "command exited non-zero")
+	assert.False(t, transient(&sftp.StatusError{}), "sftp operation failed")
+	assert.False(t, transient(&os.PathError{Op: "open", Path: "x", Err: os.ErrNotExist}), "local file missing")
+	assert.False(t, transient(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)), "timeout")
+	assert.False(t, transient(nil), "no error")
+}
+
+func Test_RemoteRetriesTransientFailureAndReconnects(t *testing.T) {
+	r, dialed := retryingRemote(2)
+
+	attempts := 0
+	var err error
+	printed := capture(func(_ string, _ Format) {
+		err = r.retry(context.Background(), "'sudo docker compose up'", func() (bool, error) {
+			attempts++
+			if attempts == 1 {
+				return true, io.EOF
+			}
+			return false, nil
+		})
+	}, "", FormatDocumentation)
+
+	assert.NoError(t, err, "succeeds on retry")
+	assert.Equal(t, 2, attempts, "attempts")
+	assert.Equal(t, 1, *dialed, "reconnected once")
+	assert.Contains(t, printed, "Retrying 'sudo docker compose up' as deploy@finch.example.com in 0s after transient failure: EOF (retry 1 of 2)", "retry report")
+}
+
+func Test_RemoteDoesNotRetryPermanentFailure(t *testing.T) {
+	r, dialed := retryingRemote(2)
+
+	attempts := 0
+	err := r.retry(context.Background(), "'false'", func() (bool, error) {
+		attempts++
+		return false, errors.New("exit status 1")
+	})
+
+	assert.EqualError(t, err, "exit status 1", "permanent error")
+	assert.Equal(t, 1, attempts, "attempts")
+	assert.Equal(t, 0, *dialed, "not reconnected")
+}
+
+func Test_RemoteGivesUpAfterRetries(t *testing.T) {
+	r, dialed := retryingRemote(2)
+
+	attempts := 0
+	err := r.retry(context.Background(), "'true'", func() (bool, error) {
+		attempts++
+		return true, io.EOF
+	})
+
+	assert.ErrorIs(t, err, io.EOF, "last transient error")
+	assert.Equal(t, 3, attempts, "attempts")
+	assert.Equal(t, 2, *dialed, "reconnected before each retry")
+}
+
+func Test_IdempotentMarksCommandsSafeToRepeat(t *testing.T) {
+	ctx := context.Background()
+	assert.False(t, isIdempotent(ctx), "commands are not repeated by default")
+
+	ctx, cancel := context.WithTimeout(Idempotent(ctx), time.Second)
+	defer cancel()
+	assert.True(t, isIdempotent(ctx), "marked context")
+}
@@ -0,0 +1,186 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+type replay struct {
+	Fixture string
+	calls   []call
+	used    []bool
+	mutex   sync.Mutex
+	format  Format
+	out     io.Writer
+	dryRun  bool
+}
+
+// NewReplay returns a target serving the calls recorded in the fixture file.
+// Run and Stream calls are matched by command, Copy calls by destination,
+// mode and owner, Fetch calls by source. The optional "match" field of a
+// recorded call holds a regular expression used instead of the command,
+// destination or source. Each recorded call is served once, in the order of the fixture.
+func NewReplay(file string, opts Options) (Target, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	r := &replay{
+		Fixture: filepath.Base(file),
+		format:  opts.Format,
+		out:     opts.Output,
+		dryRun:  opts.DryRun,
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var c call
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s:%d: %w", file, line, err)
+		}
+		if c.Match != "" {
+			if _, err := regexp.Compile(c.Match); err != nil {
+				return nil, fmt.Errorf("invalid fixture %s:%d: %w", file, line, err)
+			}
+		}
+		r.calls = append(r.calls, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	r.used = make([]bool, len(r.calls))
+
+	return r, nil
+}
+
+func (r *replay) Run(ctx context.Context, cmd string) ([]byte, error) {
+	FprintProgress(r.out, fmt.Sprintf("Running '%s' as replay@%s", cmd, r.Fixture), r.format)
+	if r.dryRun {
+		return nil, nil
+	}
+
+	return r.run(cmd, r.format)
+}
+
+func (r *replay) RunForce(ctx context.Context, cmd string) ([]byte, error) {
+	FprintProgress(r.out, fmt.Sprintf("Running '%s' as replay@%s", cmd, r.Fixture), r.format)
+
+	return r.run(cmd, FormatQuiet)
+}
+
+func (r *replay) run(cmd string, format Format) ([]byte, error) {
+	out, err := r.serve(func(c call) bool {
+		return c.Method == "run" && c.matches(c.Command, cmd)
+	}, fmt.Sprintf("run '%s'", cmd))
+	if out != nil {
+		o := newOutput(cmd, format, r.out)
+		_, _ = o.Writer("stdout").Write(out)
+		out = o.Bytes()
+	}
+
+	return out, err
+}
+
+func (r *replay) Stream(ctx context.Context, cmd string, w io.Writer) error {
+	FprintProgress(r.out, fmt.Sprintf("Running '%s' as replay@%s", cmd, r.Fixture), r.format)
+
+	out, err := r.serve(func(c call) bool {
+		return c.Method == "stream" && c.matches(c.Command, cmd)
+	}, fmt.Sprintf("stream '%s'", cmd))
+	if out != nil {
+		_, _ = w.Write(out)
+	}
+
+	return err
+}
+
+func (r *replay) Copy(ctx context.Context, src, dest, mode, owner string) ([]byte, error) {
+	FprintProgress(r.out, fmt.Sprintf("Copying from '%s' to '%s' as replay@%s", src, dest, r.Fixture), r.format)
+	if r.dryRun {
+		return nil, nil
+	}
+
+	return r.serve(func(c call) bool {
+		return c.Method == "copy" && c.matches(c.Dest, dest) && c.Mode == mode && c.Owner == owner
+	}, fmt.Sprintf("copy to '%s'", dest))
+}
+
+func (r *replay) Fetch(ctx context.Context, src, dest string) ([]byte, error) {
+	FprintProgress(r.out, fmt.Sprintf("Fetching from '%s' to '%s' as replay@%s", src, dest, r.Fixture), r.format)
+	if r.dryRun {
+		return nil, nil
+	}
+
+	var content string
+	out, err := r.serve(func(c call) bool {
+		if c.Method != "fetch" || !c.matches(c.Src, src) {
+			return false
+		}
+		content = c.Content
+		return true
+	}, fmt.Sprintf("fetch from '%s'", src))
+	if err != nil {
+		return out, err
+	}
+
+	if err := os.WriteFile(dest, []byte(content), 0600); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (r *replay) serve(match func(call) bool, desc string) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, c := range r.calls {
+		if r.used[i] || !match(c) {
+			continue
+		}
+		r.used[i] = true
+
+		var err error
+		if c.Error != "" {
+			err = errors.New(c.Error)
+		}
+		if err != nil && c.Status != 0 {
+			err = &ExitError{Status: c.Status, Err: err}
+		}
+		if c.Output == "" {
+			return nil, err
+		}
+		return []byte(c.Output), err
+	}
+
+	return nil, fmt.Errorf("no recorded call for %s in %s", desc, r.Fixture)
+}
+
+func (c call) matches(recorded, actual string) bool {
+	if c.Match != "" {
+		return regexp.MustCompile(c.Match).MatchString(actual)
+	}
+
+	return recorded == actual
+}
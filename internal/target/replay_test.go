@@ -0,0 +1,117 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RecorderWritesCallsThatReplayServesBack(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.jsonl")
+	ctx := context.Background()
+
+	local, err := New("localhost", Options{CmdTimeout: 10 * time.Second})
+	assert.NoError(t, err, "create local target")
+	recorder := NewRecorder(local, fixture)
+
+	out, err := recorder.Run(ctx, "echo hello")
+	assert.NoError(t, err, "run command")
+	assert.Equal(t, "hello\n", string(out), "command output")
+
+	_, err = recorder.Run(ctx, "echo oops >&2; exit 3")
+	assert.Error(t, err, "run failing command")
+
+	replay, err := NewReplay(fixture, Options{})
+	assert.NoError(t, err, "load fixture")
+
+	_, err = replay.Run(ctx, "echo oops >&2; exit 3")
+	assert.EqualError(t, err, "exit status 3", "replayed error")
+
+	out, err = replay.Run(ctx, "echo hello")
+	assert.NoError(t, err, "replay command")
+	assert.Equal(t, "hello\n", string(out), "replayed output")
+
+	_, err = replay.Run(ctx, "echo hello")
+	assert.ErrorContains(t, err, "no recorded call for run 'echo hello'", "calls are served once")
+}
+
+func Test_ReplayMatchesRegularExpression(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.jsonl")
+	err := os.WriteFile(fixture, []byte(
+		`{"method":"run","match":"^curl -o /tmp/alloy-[0-9]+\\.zip","output":"ok"}`+"\n"+
+			`{"method":"copy","match":"^/etc/alloy/.+\\.alloy$","mode":"400","owner":"0:0"}`+"\n",
+	), 0600)
+	assert.NoError(t, err, "write fixture")
+
+	replay, err := NewReplay(fixture, Options{})
+	assert.NoError(t, err, "load fixture")
+
+	out, err := replay.Run(context.Background(), "curl -o /tmp/alloy-1760.zip")
+	assert.NoError(t, err, "replay command")
+	assert.Equal(t, "ok", string(out), "replayed output")
+
+	_, err = replay.Copy(context.Background(), "/tmp/x", "/etc/alloy/config.alloy", "600", "0:0")
+	assert.ErrorContains(t, err, "no recorded call for copy to '/etc/alloy/config.alloy'", "mode mismatch")
+
+	_, err = replay.Copy(context.Background(), "/tmp/x", "/etc/alloy/config.alloy", "400", "0:0")
+	assert.NoError(t, err, "replay copy")
+}
+
+func Test_RecorderWritesFetchedContentThatReplayRestores(t *testing.T) {
+	dir := t.TempDir()
+	fixture := filepath.Join(dir, "fixture.jsonl")
+	src := filepath.Join(dir, "finch.json")
+	err := os.WriteFile(src, []byte(`{"hostname":"finch.example.com"}`), 0600)
+	assert.NoError(t, err, "write source")
+
+	local, err := New("localhost", Options{CmdTimeout: 10 * time.Second})
+	assert.NoError(t, err, "create local target")
+
+	dest := filepath.Join(dir, "fetched.json")
+	_, err = NewRecorder(local, fixture).Fetch(context.Background(), src, dest)
+	assert.NoError(t, err, "fetch file")
+
+	replay, err := NewReplay(fixture, Options{})
+	assert.NoError(t, err, "load fixture")
+
+	restored := filepath.Join(dir, "restored.json")
+	_, err = replay.Fetch(context.Background(), src, restored)
+	assert.NoError(t, err, "replay fetch")
+
+	content, err := os.ReadFile(restored)
+	assert.NoError(t, err, "read restored file")
+	assert.Equal(t, `{"hostname":"finch.example.com"}`, string(content), "restored content")
+}
+
+func Test_RecorderStreamsThroughAndReplayServesBack(t *testing.T) {
+	fixture := filepath.Join(t.TempDir(), "fixture.jsonl")
+
+	local, err := New("localhost", Options{CmdTimeout: 10 * time.Second})
+	assert.NoError(t, err, "create local target")
+	recorder := NewRecorder(local, fixture)
+
+	streamer, ok := recorder.(Streamer)
+	assert.True(t, ok, "recorder implements Streamer")
+
+	var out bytes.Buffer
+	err = streamer.Stream(context.Background(), "echo hello", &out)
+	assert.NoError(t, err, "stream command")
+	assert.Equal(t, "hello\n", out.String(), "streamed output")
+
+	replay, err := NewReplay(fixture, Options{})
+	assert.NoError(t, err, "load fixture")
+
+	out.Reset()
+	err = replay.(Streamer).Stream(context.Background(), "echo hello", &out)
+	assert.NoError(t, err, "replay stream")
+	assert.Equal(t, "hello\n", out.String(), "replayed output")
+}
@@ -0,0 +1,202 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kevinburke/ssh_config"
+)
+
+type hostConfig struct {
+	Alias         string
+	Hostname      string
+	Port          uint
+	User          string
+	IdentityFiles []string
+	Jumps         []string
+}
+
+type sshConfig struct {
+	files []*ssh_config.Config
+}
+
+var sshConfigFiles = func() []string {
+	files := []string{}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".ssh", "config"))
+	}
+	return append(files, "/etc/ssh/ssh_config")
+}
+
+var defaultIdentityFiles = []string{
+	"~/.ssh/id_ed25519",
+	"~/.ssh/id_ecdsa",
+	"~/.ssh/id_rsa",
+}
+
+// sshConfigWarned holds the config files already warned about as
+// unparseable, each is warned about once per run.
+var sshConfigWarned sync.Map
+var sshConfigWarnings io.Writer = os.Stderr
+
+func loadSSHConfig(files ...string) (*sshConfig, error) {
+	c := &sshConfig{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		cfg, err := ssh_config.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			// The parser rejects valid OpenSSH directives like Match final
+			// or Match exec, the explicit options still apply.
+			if _, warned := sshConfigWarned.LoadOrStore(file, true); !warned {
+				_, _ = fmt.Fprintf(sshConfigWarnings, "Warning: Ignoring %s, failed to parse: %s\n", file, err)
+			}
+			continue
+		}
+		c.files = append(c.files, cfg)
+	}
+
+	return c, nil
+}
+
+func (c *sshConfig) get(alias, key string) string {
+	for _, cfg := range c.files {
+		if val, err := cfg.Get(alias, key); err == nil && val != "" {
+			return val
+		}
+	}
+
+	return ""
+}
+
+func (c *sshConfig) getAll(alias, key string) []string {
+	var all []string
+	for _, cfg := range c.files {
+		if val, err := cfg.GetAll(alias, key); err == nil {
+			all = append(all, val...)
+		}
+	}
+
+	return all
+}
+
+func resolveHostConfig(host *url.URL, opts SSHOptions, cfg *sshConfig) (*hostConfig, error) {
+	alias := host.Hostname()
+	hc := &hostConfig{
+		Alias:    alias,
+		Hostname: alias,
+		Port:     22,
+		User:     host.User.Username(),
+	}
+
+	if hostname := cfg.get(alias, "HostName"); hostname != "" {
+		hc.Hostname = strings.ReplaceAll(hostname, "%h", alias)
+	}
+
+	if hc.User == "" {
+		hc.User = cfg.get(alias, "User")
+	}
+	if hc.User == "" {
+		hc.User = localUsername()
+	}
+
+	port := host.Port()
+	if port == "" {
+		port = cfg.get(alias, "Port")
+	}
+	if port != "" {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q: %w", port, err)
+		}
+		hc.Port = uint(p)
+	}
+
+	if opts.IdentityFile != "" {
+		hc.IdentityFiles = append(hc.IdentityFiles, opts.IdentityFile)
+	}
+	identities := cfg.getAll(alias, "IdentityFile")
+	if len(identities) == 0 && opts.IdentityFile == "" {
+		identities = defaultIdentityFiles
+	}
+	for _, identity := range identities {
+		hc.IdentityFiles = append(hc.IdentityFiles, hc.expand(identity))
+	}
+
+	jump := opts.Jump
+	if jump == "" {
+		jump = cfg.get(alias, "ProxyJump")
+	}
+	if jump != "" && jump != "none" {
+		for _, j := range strings.Split(jump, ",") {
+			hc.Jumps = append(hc.Jumps, strings.TrimSpace(j))
+		}
+	}
+
+	return hc, nil
+}
+
+func resolveJumpHosts(hc *hostConfig, opts SSHOptions, cfg *sshConfig) ([]*hostConfig, error) {
+	var jumps []*hostConfig
+	for _, jump := range hc.Jumps {
+		host, err := parseHostUrl(strings.TrimPrefix(jump, "ssh://"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid jump host %q: %w", jump, err)
+		}
+
+		jc, err := resolveHostConfig(host, SSHOptions{IdentityFile: opts.IdentityFile}, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jump host %q: %w", jump, err)
+		}
+		jc.Jumps = nil
+		jumps = append(jumps, jc)
+	}
+
+	return jumps, nil
+}
+
+func (hc *hostConfig) expand(path string) string {
+	home, _ := os.UserHomeDir()
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = home + strings.TrimPrefix(path, "~")
+	}
+
+	return strings.NewReplacer(
+		"%%", "%",
+		"%d", home,
+		"%h", hc.Hostname,
+		"%n", hc.Alias,
+		"%p", strconv.Itoa(int(hc.Port)),
+		"%r", hc.User,
+		"%u", localUsername(),
+	).Replace(path)
+}
+
+func localUsername() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+
+	return username
+}
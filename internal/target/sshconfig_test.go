@@ -0,0 +1,171 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sshConfigContent = `
+Host prod-web-1
+    HostName 10.19.80.11
+    User deploy
+    Port 2222
+    IdentityFile ~/.ssh/prod_ed25519
+
+Host *.internal
+    HostName %h.example.com
+    User ops
+    ProxyJump admin@bastion:2200, gateway
+
+Host gateway
+    HostName 203.0.113.1
+    User jump
+    ProxyJump none
+`
+
+func writeSSHConfig(t *testing.T) *sshConfig {
+	file := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(file, []byte(sshConfigContent), 0600)
+	assert.NoError(t, err, "write ssh config")
+
+	cfg, err := loadSSHConfig(file, filepath.Join(t.TempDir(), "missing"))
+	assert.NoError(t, err, "load ssh config")
+
+	return cfg
+}
+
+func Test_ResolveHostConfigHonorsHostAlias(t *testing.T) {
+	cfg := writeSSHConfig(t)
+	home, _ := os.UserHomeDir()
+
+	host, _ := parseHostUrl("prod-web-1")
+	hc, err := resolveHostConfig(host, SSHOptions{}, cfg)
+	assert.NoError(t, err, "resolve host config")
+
+	assert.Equal(t, "10.19.80.11", hc.Hostname, "hostname")
+	assert.Equal(t, "deploy", hc.User, "user")
+	assert.Equal(t, uint(2222), hc.Port, "port")
+	assert.Equal(t, []string{filepath.Join(home, ".ssh/prod_ed25519")}, hc.IdentityFiles, "identity files")
+}
+
+func Test_ResolveHostConfigPrefersExplicitValues(t *testing.T) {
+	cfg := writeSSHConfig(t)
+
+	host, _ := parseHostUrl("root@prod-web-1:22")
+	hc, err := resolveHostConfig(host, SSHOptions{IdentityFile: "/keys/ci"}, cfg)
+	assert.NoError(t, err, "resolve host config")
+
+	assert.Equal(t, "root", hc.User, "user")
+	assert.Equal(t, uint(22), hc.Port, "port")
+	assert.Equal(t, "/keys/ci", hc.IdentityFiles[0], "explicit identity first")
+	assert.Len(t, hc.IdentityFiles, 2, "identity files")
+}
+
+func Test_ResolveHostConfigExpandsHostPattern(t *testing.T) {
+	cfg := writeSSHConfig(t)
+	home, _ := os.UserHomeDir()
+
+	host, _ := parseHostUrl("db.internal")
+	hc, err := resolveHostConfig(host, SSHOptions{}, cfg)
+	assert.NoError(t, err, "resolve host config")
+
+	assert.Equal(t, "db.internal.example.com", hc.Hostname, "hostname")
+	assert.Equal(t, "ops", hc.User, "user")
+	assert.Equal(t, uint(22), hc.Port, "port")
+	assert.Equal(t, filepath.Join(home, ".ssh/id_ed25519"), hc.IdentityFiles[0], "default identity files")
+}
+
+func Test_ResolveHostConfigFallsBackToLocalUser(t *testing.T) {
+	cfg := writeSSHConfig(t)
+
+	host, _ := parseHostUrl("example.com")
+	hc, err := resolveHostConfig(host, SSHOptions{}, cfg)
+	assert.NoError(t, err, "resolve host config")
+
+	assert.Equal(t, "example.com", hc.Hostname, "hostname")
+	assert.Equal(t, localUsername(), hc.User, "user")
+}
+
+func Test_ResolveJumpHostsFromProxyJump(t *testing.T) {
+	cfg := writeSSHConfig(t)
+
+	host, _ := parseHostUrl("db.internal")
+	hc, err := resolveHostConfig(host, SSHOptions{}, cfg)
+	assert.NoError(t, err, "resolve host config")
+	assert.Equal(t, []string{"admin@bastion:2200", "gateway"}, hc.Jumps, "jumps")
+
+	jumps, err := resolveJumpHosts(hc, SSHOptions{}, cfg)
+	assert.NoError(t, err, "resolve jump hosts")
+	assert.Len(t, jumps, 2, "jump hosts")
+
+	assert.Equal(t, "bastion", jumps[0].Hostname, "first jump hostname")
+	assert.Equal(t, "admin", jumps[0].User, "first jump user")
+	assert.Equal(t, uint(2200), jumps[0].Port, "first jump port")
+
+	assert.Equal(t, "203.0.113.1", jumps[1].Hostname, "second jump hostname")
+	assert.Equal(t, "jump", jumps[1].User, "second jump user")
+	assert.Empty(t, jumps[1].Jumps, "no nested jumps")
+}
+
+func Test_ResolveJumpHostsPrefersExplicitJump(t *testing.T) {
+	cfg := writeSSHConfig(t)
+
+	host, _ := parseHostUrl("db.internal")
+	hc, err := resolveHostConfig(host, SSHOptions{Jump: "ssh://root@10.0.0.1"}, cfg)
+	assert.NoError(t, err, "resolve host config")
+
+	jumps, err := resolveJumpHosts(hc, SSHOptions{}, cfg)
+	assert.NoError(t, err, "resolve jump hosts")
+	assert.Len(t, jumps, 1, "jump hosts")
+	assert.Equal(t, "10.0.0.1", jumps[0].Hostname, "jump hostname")
+	assert.Equal(t, "root", jumps[0].User, "jump user")
+
+	host, _ = parseHostUrl("gateway")
+	hc, err = resolveHostConfig(host, SSHOptions{}, cfg)
+	assert.NoError(t, err, "resolve host config")
+	assert.Empty(t, hc.Jumps, "ProxyJump none")
+}
+
+func Test_LoadSSHConfigIgnoresUnparseableFile(t *testing.T) {
+	var warnings bytes.Buffer
+	orig := sshConfigWarnings
+	sshConfigWarnings = &warnings
+	defer func() { sshConfigWarnings = orig }()
+
+	dir := t.TempDir()
+	system := filepath.Join(dir, "ssh_config")
+	err := os.WriteFile(system, []byte("Match final all\n    GSSAPIAuthentication yes\n\nMatch exec \"true\"\n    User ops\n"), 0600)
+	assert.NoError(t, err, "write system ssh config")
+	user := filepath.Join(dir, "config")
+	err = os.WriteFile(user, []byte(sshConfigContent), 0600)
+	assert.NoError(t, err, "write user ssh config")
+
+	for range 2 {
+		cfg, err := loadSSHConfig(user, system)
+		assert.NoError(t, err, "load ssh config")
+
+		host, _ := parseHostUrl("prod-web-1")
+		hc, err := resolveHostConfig(host, SSHOptions{}, cfg)
+		assert.NoError(t, err, "resolve host config")
+		assert.Equal(t, "10.19.80.11", hc.Hostname, "parseable config applies")
+	}
+
+	assert.Equal(t, 1, strings.Count(warnings.String(), "Warning: Ignoring "+system), "warned once")
+
+	cfg, err := loadSSHConfig(system)
+	assert.NoError(t, err, "load unparseable ssh config only")
+	host, _ := parseHostUrl("deploy@10.19.80.12:2200")
+	hc, err := resolveHostConfig(host, SSHOptions{}, cfg)
+	assert.NoError(t, err, "resolve host config")
+	assert.Equal(t, "deploy", hc.User, "explicit user")
+	assert.Equal(t, uint(2200), hc.Port, "explicit port")
+}
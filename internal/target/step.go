@@ -0,0 +1,146 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+type stepEvent struct {
+	Timestamp  string   `json:"timestamp"`
+	Event      string   `json:"event"`
+	Operation  string   `json:"operation"`
+	Host       string   `json:"host,omitempty"`
+	Step       string   `json:"step,omitempty"`
+	Phase      string   `json:"phase,omitempty"`
+	Status     string   `json:"status,omitempty"`
+	ExitStatus *int     `json:"exit_status,omitempty"`
+	Duration   *float64 `json:"duration,omitempty"`
+	Steps      *int     `json:"steps,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Tracker times the steps of an operation. In JSON format it prints an event
+// when a step starts and ends, and a summary when the operation is done. A
+// nil Tracker just runs the steps.
+type Tracker struct {
+	operation   string
+	host        string
+	format      Format
+	w           io.Writer
+	start       time.Time
+	steps       int
+	failedStep  string
+	failedPhase string
+}
+
+// NewTracker returns a Tracker for the operation on host.
+func NewTracker(operation, host string, format Format, w io.Writer) *Tracker {
+	return &Tracker{
+		operation: operation,
+		host:      host,
+		format:    format,
+		w:         stdout(w),
+		start:     time.Now(),
+	}
+}
+
+// Step runs f as the step id, the phase is the part of id before the first
+// dot.
+func (t *Tracker) Step(id string, f func() error) error {
+	if t == nil {
+		return f()
+	}
+
+	phase, _, _ := strings.Cut(id, ".")
+	t.print(stepEvent{Event: "step_start", Step: id, Phase: phase})
+
+	start := time.Now()
+	err := f()
+	duration := time.Since(start).Seconds()
+	status := exitStatus(err)
+
+	t.steps++
+	event := stepEvent{
+		Event:      "step_end",
+		Step:       id,
+		Phase:      phase,
+		Status:     "success",
+		ExitStatus: &status,
+		Duration:   &duration,
+	}
+	if err != nil {
+		event.Status = "failure"
+		event.Error = err.Error()
+		if t.failedStep == "" {
+			t.failedStep = id
+			t.failedPhase = phase
+		}
+	}
+	t.print(event)
+
+	return err
+}
+
+// Summary prints the result of the operation, err is the error it returned.
+func (t *Tracker) Summary(err error) {
+	if t == nil {
+		return
+	}
+
+	duration := time.Since(t.start).Seconds()
+	status := exitStatus(err)
+	event := stepEvent{
+		Event:      "summary",
+		Status:     "success",
+		ExitStatus: &status,
+		Duration:   &duration,
+		Steps:      &t.steps,
+	}
+	if err != nil {
+		event.Status = "failure"
+		event.Step = t.failedStep
+		event.Phase = t.failedPhase
+		event.Error = err.Error()
+	}
+	t.print(event)
+}
+
+func (t *Tracker) print(event stepEvent) {
+	if t.format != FormatJSON {
+		return
+	}
+
+	event.Timestamp = time.Now().Format(time.RFC3339)
+	event.Operation = t.operation
+	event.Host = t.host
+	data, err := json.Marshal(event)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+		return
+	}
+	_, _ = fmt.Fprintln(t.w, string(data))
+}
+
+// exitStatus returns the exit status of the command that caused err, 1 if it
+// is unknown and 0 if err is nil.
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Status
+	}
+
+	return 1
+}
@@ -0,0 +1,71 @@
+/*
+Copyright (c) Tobias Schäfer. All rights reserved.
+Licensed under the MIT license, see LICENSE in the project root for details.
+*/
+package target
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TrackerPrintsStepEventsAndSummary(t *testing.T) {
+	printed := capture(func(_ string, format Format) {
+		tracker := NewTracker("service.deploy", "ssh://root@10.19.80.100", format, nil)
+		_ = tracker.Step("deploy.make-dir-hierarchy", func() error { return nil })
+		err := tracker.Step("deploy.compose-up", func() error {
+			return fmt.Errorf("compose up: %w", &ExitError{Status: 17, Err: errors.New("Process exited with status 17")})
+		})
+		_ = tracker.Step("deploy.compose-ready", func() error { return errors.New("exit status 3") })
+		tracker.Summary(err)
+	}, "", FormatJSON)
+
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(printed), "\n") {
+		var event map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &event), "unmarshal event")
+		events = append(events, event)
+	}
+	assert.Len(t, events, 7, "number of events")
+
+	assert.Equal(t, "step_start", events[0]["event"], "start event")
+	assert.Equal(t, "deploy", events[0]["phase"], "phase")
+	assert.Equal(t, "service.deploy", events[0]["operation"], "operation")
+	assert.Equal(t, "ssh://root@10.19.80.100", events[0]["host"], "host")
+
+	assert.Equal(t, "step_end", events[1]["event"], "end event")
+	assert.Equal(t, "success", events[1]["status"], "status")
+	assert.Equal(t, float64(0), events[1]["exit_status"], "exit status")
+	assert.Contains(t, events[1], "duration", "duration")
+
+	assert.Equal(t, "failure", events[3]["status"], "failed status")
+	assert.Equal(t, float64(17), events[3]["exit_status"], "command exit status")
+	assert.Equal(t, float64(1), events[5]["exit_status"], "unknown exit status")
+
+	summary := events[6]
+	assert.Equal(t, "summary", summary["event"], "summary event")
+	assert.Equal(t, "failure", summary["status"], "summary status")
+	assert.Equal(t, "deploy.compose-up", summary["step"], "first failed step")
+	assert.Equal(t, "deploy", summary["phase"], "failed phase")
+	assert.Equal(t, float64(3), summary["steps"], "steps run")
+}
+
+func Test_TrackerPrintsNothingBesideJSON(t *testing.T) {
+	printed := capture(func(_ string, format Format) {
+		tracker := NewTracker("agent.deploy", "localhost", format, nil)
+		_ = tracker.Step("deploy.copy-config-file", func() error { return nil })
+		tracker.Summary(nil)
+	}, "", FormatDocumentation)
+	assert.Empty(t, printed, "no events")
+
+	var tracker *Tracker
+	ran := false
+	err := tracker.Step("deploy.copy-config-file", func() error { ran = true; return nil })
+	assert.NoError(t, err)
+	assert.True(t, ran, "nil tracker runs step")
+}
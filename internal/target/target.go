@@ -7,13 +7,21 @@ package target
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
-	"os/user"
+	"os/exec"
 	"slices"
 	"strings"
 	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	TargetRecordEnv string = "FINCH_TARGET_RECORD"
 )
 
 type Format int64
@@ -25,27 +33,140 @@ const (
 	FormatJSON          Format = 3
 )
 
+type HostKeyPolicy int64
+
+const (
+	HostKeyAcceptNew HostKeyPolicy = 0
+	HostKeyStrict    HostKeyPolicy = 1
+	HostKeyInsecure  HostKeyPolicy = 2
+)
+
+// Target runs commands and transfers files on a host. Run streams the command
+// output in documentation and JSON format. RunForce runs the command even in
+// dry-run mode, it is meant for queries and does not stream the output. Copy
+// uploads a local file, Fetch downloads a file readable by the superuser.
+// Remote targets reconnect and retry a command failing to start due to a
+// connection error. A started command is only repeated if it is marked
+// Idempotent. Uploads and downloads are retried as a whole.
 type Target interface {
 	Run(ctx context.Context, command string) ([]byte, error)
 	RunForce(ctx context.Context, command string) ([]byte, error)
 	Copy(ctx context.Context, src, dest, mode, owner string) ([]byte, error)
+	Fetch(ctx context.Context, src, dest string) ([]byte, error)
+}
+
+// Streamer is implemented by targets able to run a long-running command, it
+// writes the combined output to w as it arrives. The command is not subject
+// to the command timeout and not retried, it runs until it exits or ctx is
+// done.
+type Streamer interface {
+	Stream(ctx context.Context, command string, w io.Writer) error
+}
+
+type idempotentKey struct{}
+
+// Idempotent marks the commands run with the returned context as safe to
+// repeat, a remote target runs them again after the connection failed while
+// they ran.
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(ctx context.Context) bool {
+	idempotent, _ := ctx.Value(idempotentKey{}).(bool)
+	return idempotent
+}
+
+// ExitError is returned by a target for a command that ran and exited with a
+// non-zero status.
+type ExitError struct {
+	Status int
+	Err    error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// exitError returns err as ExitError if it reports a non-zero exit status of
+// a local or remote command.
+func exitError(err error) error {
+	var execErr *exec.ExitError
+	var sshErr *ssh.ExitError
+	switch {
+	case errors.As(err, &execErr) && execErr.ExitCode() > 0:
+		return &ExitError{Status: execErr.ExitCode(), Err: err}
+	case errors.As(err, &sshErr) && sshErr.ExitStatus() > 0:
+		return &ExitError{Status: sshErr.ExitStatus(), Err: err}
+	}
+
+	return err
+}
+
+type SSHOptions struct {
+	HostKeyPolicy HostKeyPolicy
+	IdentityFile  string
+	Jump          string
+}
+
+type RetryOptions struct {
+	Retries uint
+	Backoff time.Duration
 }
 
 type Options struct {
 	Format     Format
 	DryRun     bool
+	PlanOut    string
 	CmdTimeout time.Duration
+	Escalation Escalation
+	Retry      RetryOptions
+	SSH        SSHOptions
+	Output     io.Writer
 }
 
 var newRemoteTarget = newRemote
 var newLocalTarget = newLocal
+var newContainerTarget = newContainer
 
 func New(hostUrl string, opts Options) (Target, error) {
+	t, err := newTarget(hostUrl, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.PlanOut != "" {
+		t, err = NewPlanner(t, opts.PlanOut, hostUrl, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if file := os.Getenv(TargetRecordEnv); file != "" {
+		return NewRecorder(t, file), nil
+	}
+
+	return t, nil
+}
+
+func newTarget(hostUrl string, opts Options) (Target, error) {
 	host, err := parseHostUrl(hostUrl)
 	if err != nil {
 		return nil, err
 	}
 
+	switch host.Scheme {
+	case "host", "ssh":
+	case "docker":
+		return newContainerTarget(host, opts)
+	default:
+		return nil, fmt.Errorf("unsupported target scheme %s", host.Scheme)
+	}
+
 	local := []string{
 		"localhost",
 		"local",
@@ -60,7 +181,7 @@ func New(hostUrl string, opts Options) (Target, error) {
 }
 
 func parseHostUrl(hostUrl string) (host *url.URL, err error) {
-	if !strings.HasPrefix(hostUrl, "host://") {
+	if !strings.Contains(hostUrl, "://") {
 		hostUrl = "host://" + hostUrl
 	}
 	host, err = url.Parse(hostUrl)
@@ -68,29 +189,22 @@ func parseHostUrl(hostUrl string) (host *url.URL, err error) {
 		return nil, fmt.Errorf("invalid host URL: %w", err)
 	}
 
-	username := host.User.Username()
-	if username == "" {
-		username = "unknown"
-		user, err := user.Current()
-		if err == nil {
-			username = user.Username
-		}
-	}
-	host.User = url.User(username)
-	port := host.Port()
-	if port == "" {
-		host.Host = fmt.Sprintf("%s:%s", host.Hostname(), "22")
-	}
-
 	return host, nil
 }
 
 func PrintProgress(message string, format Format) {
+	FprintProgress(os.Stdout, message, format)
+}
+
+// FprintProgress prints like PrintProgress to w, standard output if w is nil.
+func FprintProgress(w io.Writer, message string, format Format) {
+	w = stdout(w)
+
 	switch format {
 	case FormatProgress:
-		fmt.Print(".")
+		_, _ = fmt.Fprint(w, ".")
 	case FormatDocumentation:
-		fmt.Println(message)
+		_, _ = fmt.Fprintln(w, message)
 	case FormatJSON:
 		data := map[string]string{
 			"timestamp": time.Now().Format(time.RFC3339),
@@ -101,10 +215,18 @@ func PrintProgress(message string, format Format) {
 			fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
 			return
 		}
-		fmt.Println(string(jsonData))
+		_, _ = fmt.Fprintln(w, string(jsonData))
 	case FormatQuiet:
 		// Do nothing
 	default:
-		fmt.Println(".")
+		_, _ = fmt.Fprintln(w, ".")
+	}
+}
+
+func stdout(w io.Writer) io.Writer {
+	if w == nil {
+		return os.Stdout
 	}
+
+	return w
 }